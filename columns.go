@@ -0,0 +1,41 @@
+package esperanto
+
+import "github.com/wroge/scan"
+
+// The column constructors of wroge/scan are re-exported, so that columns
+// can be built without importing scan. They return the scan types, so both can be mixed.
+
+// Any is scan.Any.
+func Any[T, V any](setter func(*T, V)) *scan.AnyColumn[T, V] {
+	return scan.Any(setter)
+}
+
+// AnyErr is scan.AnyErr.
+func AnyErr[T, V any](setter func(*T, V) error) *scan.AnyColumn[T, V] {
+	return scan.AnyErr(setter)
+}
+
+// Null is scan.Null.
+func Null[T, V any](def V, setter func(*T, V)) *scan.AnyColumn[T, *V] {
+	return scan.Null(def, setter)
+}
+
+// NullErr is scan.NullErr.
+func NullErr[T, V any](def V, setter func(*T, V) error) *scan.AnyColumn[T, *V] {
+	return scan.NullErr(def, setter)
+}
+
+// JSON is scan.JSON.
+func JSON[T, V any](setter func(*T, V)) *scan.AnyColumn[T, []byte] {
+	return scan.JSON(setter)
+}
+
+// JSONErr is scan.JSONErr.
+func JSONErr[T, V any](setter func(*T, V) error) *scan.AnyColumn[T, []byte] {
+	return scan.JSONErr(setter)
+}
+
+// Columns collects columns of T, e.g. as the second return value of a Queryable.
+func Columns[T any](columns ...scan.Column[T]) []scan.Column[T] {
+	return columns
+}
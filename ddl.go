@@ -0,0 +1,417 @@
+//nolint:ireturn
+package esperanto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wroge/superbasic"
+)
+
+// RefAction is a referential action of a foreign key. The empty action renders no clause.
+type RefAction string
+
+const (
+	NoAction   RefAction = "NO ACTION"
+	Restrict   RefAction = "RESTRICT"
+	Cascade    RefAction = "CASCADE"
+	SetNull    RefAction = "SET NULL"
+	SetDefault RefAction = "SET DEFAULT"
+)
+
+func supportsRefAction(dialect Dialect, update bool, action RefAction) bool {
+	switch dialect {
+	case Postgres, Sqlite:
+		return true
+	case MySQL:
+		// InnoDB rejects SET DEFAULT.
+		return action != SetDefault
+	case SQLServer:
+		return action != Restrict
+	case Oracle:
+		if update {
+			return action == "" || action == NoAction
+		}
+
+		return action != Restrict && action != SetDefault
+	default:
+		return false
+	}
+}
+
+// ForeignKey renders a FOREIGN KEY constraint clause for CREATE TABLE.
+// An error is returned if the dialect doesn't support an action, e.g. ON UPDATE in Oracle.
+// SQLServer rejects multiple cascade paths, which can't be detected here.
+func ForeignKey(
+	dialect Dialect,
+	columns []string,
+	refTable string,
+	refColumns []string,
+	onDelete, onUpdate RefAction,
+) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer, Oracle:
+	default:
+		return missingDialect(dialect, "ForeignKey")
+	}
+
+	if !supportsRefAction(dialect, false, onDelete) {
+		return missingDialect(dialect, "ON DELETE "+string(onDelete))
+	}
+
+	if !supportsRefAction(dialect, true, onUpdate) {
+		return missingDialect(dialect, "ON UPDATE "+string(onUpdate))
+	}
+
+	sql := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		strings.Join(columns, ", "), refTable, strings.Join(refColumns, ", "))
+
+	if onDelete != "" && (dialect != Oracle || onDelete != NoAction) {
+		sql += " ON DELETE " + string(onDelete)
+	}
+
+	if onUpdate != "" && dialect != Oracle {
+		sql += " ON UPDATE " + string(onUpdate)
+	}
+
+	return keywordSQL(sql)
+}
+
+// CreateTableIfNotExists creates a table with the column and constraint definitions of body.
+// SQLServer checks OBJECT_ID and Oracle ignores ORA-00955 in a PL/SQL block.
+func CreateTableIfNotExists(dialect Dialect, name string, body superbasic.Expression) superbasic.Expression {
+	create := keywordCompile(fmt.Sprintf("CREATE TABLE %s (\n\t?\n)", name), body)
+
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		return keywordCompile(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t?\n)", name), body)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("IF OBJECT_ID(N%s, N'U') IS NULL ?", literal(name)), create)
+	case Oracle:
+		return ignoreOracleError(create, -955)
+	default:
+		return missingDialect(dialect, "CreateTableIfNotExists")
+	}
+}
+
+// DropTableIfExists drops a table if it exists.
+// SQLServer checks OBJECT_ID and Oracle ignores ORA-00942 in a PL/SQL block.
+func DropTableIfExists(dialect Dialect, name string) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		return keywordSQL("DROP TABLE IF EXISTS " + name)
+	case SQLServer:
+		return keywordSQL(fmt.Sprintf("IF OBJECT_ID(N%s, N'U') IS NOT NULL DROP TABLE %s", literal(name), name))
+	case Oracle:
+		return ignoreOracleError(keywordSQL("DROP TABLE "+name), -942)
+	default:
+		return missingDialect(dialect, "DropTableIfExists")
+	}
+}
+
+// CreateTableAs creates table from the result of a query. SQLServer selects INTO the table.
+func CreateTableAs(dialect Dialect, table string, query superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, Oracle:
+		return keywordCompile(fmt.Sprintf("CREATE TABLE %s AS ?", table), query)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("SELECT * INTO %s FROM (?) AS t", table), query)
+	default:
+		return missingDialect(dialect, "CreateTableAs")
+	}
+}
+
+// CreateTempTable creates a temporary table with the column and constraint definitions of body.
+// SQLServer prefixes name with # if needed. Oracle creates a global temporary table, whose
+// definition is permanent while the rows are private to the session.
+func CreateTempTable(dialect Dialect, name string, body superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, Sqlite:
+		return keywordCompile(fmt.Sprintf("CREATE TEMP TABLE %s (\n\t?\n)", name), body)
+	case MySQL:
+		return keywordCompile(fmt.Sprintf("CREATE TEMPORARY TABLE %s (\n\t?\n)", name), body)
+	case SQLServer:
+		if !strings.HasPrefix(name, "#") {
+			name = "#" + name
+		}
+
+		return keywordCompile(fmt.Sprintf("CREATE TABLE %s (\n\t?\n)", name), body)
+	case Oracle:
+		return keywordCompile(fmt.Sprintf("CREATE GLOBAL TEMPORARY TABLE %s (\n\t?\n) ON COMMIT PRESERVE ROWS", name),
+			body)
+	default:
+		return missingDialect(dialect, "CreateTempTable")
+	}
+}
+
+// ignoreOracleError runs a statement without arguments via EXECUTE IMMEDIATE and ignores the given SQLCODE.
+func ignoreOracleError(statement superbasic.Expression, code int) superbasic.Expression {
+	return deferred(func() superbasic.Expression {
+		if statement == nil {
+			return nil
+		}
+
+		sql, args, err := statement.ToSQL()
+		if err != nil {
+			return superbasic.Raw{Err: err}
+		}
+
+		if len(args) > 0 {
+			return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: arguments are not allowed in '%s'", sql)}
+		}
+
+		return keywordSQL(fmt.Sprintf(`BEGIN
+	EXECUTE IMMEDIATE '%s';
+EXCEPTION
+	WHEN OTHERS THEN
+		IF SQLCODE != %d THEN
+			RAISE;
+		END IF;
+END;`, strings.ReplaceAll(sql, "'", "''"), code))
+	})
+}
+
+// CommentTarget is a table or, if Column is set, a column of a table.
+// Schema is only used by SQLServer and defaults to dbo.
+type CommentTarget struct {
+	Schema string
+	Table  string
+	Column string
+}
+
+// Comment adds a comment to a table or column.
+// For MySQL columns the inline COMMENT clause of a column definition is rendered.
+// SQLServer adds the MS_Description extended property.
+func Comment(dialect Dialect, target CommentTarget, text string) superbasic.Expression {
+	switch dialect {
+	case Postgres, Oracle:
+		if target.Column != "" {
+			return keywordSQL(fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", target.Table, target.Column, literal(text)))
+		}
+
+		return keywordSQL(fmt.Sprintf("COMMENT ON TABLE %s IS %s", target.Table, literal(text)))
+	case MySQL:
+		if target.Column != "" {
+			return keywordSQL("COMMENT " + literal(text))
+		}
+
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s COMMENT = %s", target.Table, literal(text)))
+	case SQLServer:
+		schema := target.Schema
+		if schema == "" {
+			schema = "dbo"
+		}
+
+		sql := fmt.Sprintf("EXEC sp_addextendedproperty @name = N'MS_Description', @value = N%s, "+
+			"@level0type = N'SCHEMA', @level0name = N%s, @level1type = N'TABLE', @level1name = N%s",
+			literal(text), literal(schema), literal(target.Table))
+
+		if target.Column != "" {
+			sql += fmt.Sprintf(", @level2type = N'COLUMN', @level2name = N%s", literal(target.Column))
+		}
+
+		return keywordSQL(sql)
+	default:
+		return missingDialect(dialect, "Comment")
+	}
+}
+
+// ResetAutoIncrement restarts the auto increment of column at 1, e.g. after deleting seed data.
+// Sqlite only resets AUTOINCREMENT tables and Oracle redefines the identity as GENERATED BY DEFAULT.
+// SQLServer reseeds to 1 if the table is new or truncated and to 0 after deletes, so the next row gets 1
+// in both cases, assuming an increment of 1.
+func ResetAutoIncrement(dialect Dialect, table, column string) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordSQL("SELECT setval(pg_get_serial_sequence(?, ?), 1, false)", table, column)
+	case MySQL:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = 1", table))
+	case Sqlite:
+		return keywordSQL("DELETE FROM sqlite_sequence WHERE name = ?", table)
+	case SQLServer:
+		// the next identity is the reseed value on a new or truncated table, otherwise the reseed value plus 1
+		return keywordSQL(fmt.Sprintf("IF (SELECT last_value FROM sys.identity_columns WHERE object_id = OBJECT_ID(N%s)) "+
+			"IS NULL DBCC CHECKIDENT (N%s, RESEED, 1) ELSE DBCC CHECKIDENT (N%s, RESEED, 0)",
+			literal(table), literal(table), literal(table)))
+	case Oracle:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s MODIFY %s GENERATED BY DEFAULT AS IDENTITY (START WITH 1)",
+			table, column))
+	default:
+		return missingDialect(dialect, "ResetAutoIncrement")
+	}
+}
+
+// GeneratedColumn renders the definition of a computed column for CREATE TABLE.
+// Stored columns are PERSISTED in SQLServer, which ignores typ. Oracle only supports virtual columns.
+func GeneratedColumn(dialect Dialect, name, typ string, expr superbasic.Expression, stored bool) superbasic.Expression {
+	kind := "VIRTUAL"
+	if stored {
+		kind = "STORED"
+	}
+
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		return keywordCompile(fmt.Sprintf("%s %s GENERATED ALWAYS AS (?) %s", name, typ, kind), expr)
+	case SQLServer:
+		if stored {
+			return keywordCompile(name+" AS (?) PERSISTED", expr)
+		}
+
+		return keywordCompile(name+" AS (?)", expr)
+	case Oracle:
+		if stored {
+			return missingDialect(dialect, "stored GeneratedColumn")
+		}
+
+		return keywordCompile(fmt.Sprintf("%s %s GENERATED ALWAYS AS (?) VIRTUAL", name, typ), expr)
+	default:
+		return missingDialect(dialect, "GeneratedColumn")
+	}
+}
+
+// BoolDefault renders the DEFAULT clause of a boolean column, e.g. a BIT column in SQLServer
+// or a NUMBER(1) column in Oracle.
+func BoolDefault(dialect Dialect, b bool) superbasic.Expression {
+	return keywordCompile("DEFAULT ?", Bool(dialect, b))
+}
+
+// IndexOptions configures CreateIndex. Include adds non-key columns to the index in Postgres and SQLServer.
+// Where creates a partial index in Postgres and Sqlite or a filtered index in SQLServer and
+// must not contain parameters.
+type IndexOptions struct {
+	Unique      bool
+	IfNotExists bool
+	Include     []string
+	Where       superbasic.Expression
+}
+
+// CreateIndex creates an index on columns of table. Options that are not supported by the dialect
+// return a MissingDialectError. SQLServer checks sys.indexes and Oracle ignores ORA-00955 for IfNotExists.
+func CreateIndex(dialect Dialect, name, table string, columns []string, opts IndexOptions) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer, Oracle:
+	default:
+		return missingDialect(dialect, "CreateIndex")
+	}
+
+	if opts.IfNotExists && dialect == MySQL {
+		return missingDialect(dialect, "CreateIndex with IfNotExists")
+	}
+
+	if len(opts.Include) > 0 && dialect != Postgres && dialect != SQLServer {
+		return missingDialect(dialect, "CreateIndex with Include")
+	}
+
+	if opts.Where != nil && (dialect == MySQL || dialect == Oracle) {
+		return missingDialect(dialect, "CreateIndex with Where")
+	}
+
+	create := "CREATE INDEX"
+	if opts.Unique {
+		create = "CREATE UNIQUE INDEX"
+	}
+
+	if opts.IfNotExists && (dialect == Postgres || dialect == Sqlite) {
+		create += " IF NOT EXISTS"
+	}
+
+	var include superbasic.Expression = superbasic.Raw{}
+	if len(opts.Include) > 0 {
+		include = keywordSQL(fmt.Sprintf("INCLUDE (%s)", strings.Join(opts.Include, ", ")))
+	}
+
+	index := superbasic.Join(" ",
+		keywordSQL(fmt.Sprintf("%s %s ON %s (%s)", create, name, table, strings.Join(columns, ", "))),
+		include, where(opts.Where))
+
+	if opts.IfNotExists {
+		switch dialect {
+		case SQLServer:
+			return keywordCompile(fmt.Sprintf("IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N%s "+
+				"AND object_id = OBJECT_ID(N%s)) ?", literal(name), literal(table)), index)
+		case Oracle:
+			return ignoreOracleError(index, -955)
+		}
+	}
+
+	return index
+}
+
+// AlterColumnType changes the type of column. using converts the existing values in Postgres,
+// the other dialects convert implicitly and return a MissingDialectError for using.
+// MySQL redefines the whole column, so constraints like NOT NULL must be part of typ.
+// Sqlite can't alter columns and returns a MissingDialectError.
+func AlterColumnType(dialect Dialect, table, column, typ string, using superbasic.Expression) superbasic.Expression {
+	if using != nil && dialect != Postgres {
+		return missingDialect(dialect, "AlterColumnType with using")
+	}
+
+	switch dialect {
+	case Postgres:
+		alter := keywordSQL(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, column, typ))
+
+		if using == nil {
+			return alter
+		}
+
+		return keywordCompile("? USING ?", alter, using)
+	case MySQL:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, typ))
+	case SQLServer:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", table, column, typ))
+	case Oracle:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s MODIFY (%s %s)", table, column, typ))
+	default:
+		return missingDialect(dialect, "AlterColumnType")
+	}
+}
+
+// RenameTable renames a table. SQLServer calls sp_rename.
+func RenameTable(dialect Dialect, from, to string) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, Oracle:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", from, to))
+	case SQLServer:
+		return keywordSQL(fmt.Sprintf("EXEC sp_rename N%s, N%s", literal(from), literal(to)))
+	default:
+		return missingDialect(dialect, "RenameTable")
+	}
+}
+
+// RenameColumn renames a column of table. MySQL needs version 8 and SQLServer calls sp_rename.
+func RenameColumn(dialect Dialect, table, from, to string) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, Oracle:
+		return keywordSQL(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, from, to))
+	case SQLServer:
+		return keywordSQL(fmt.Sprintf("EXEC sp_rename N%s, N%s, N'COLUMN'", literal(table+"."+from), literal(to)))
+	default:
+		return missingDialect(dialect, "RenameColumn")
+	}
+}
+
+// Check renders a named check constraint for CREATE TABLE or ALTER TABLE ... ADD. The expr must not contain
+// parameters. MySQL parses but ignores check constraints before version 8.0.16.
+func Check(dialect Dialect, name string, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer, Oracle:
+		return keywordCompile(fmt.Sprintf("CONSTRAINT %s CHECK (?)", name), expr)
+	default:
+		return missingDialect(dialect, "Check")
+	}
+}
+
+// SequenceDefault renders the DEFAULT clause of a column that takes the next value of sequence.
+// MySQL and Sqlite have no sequences and return a MissingDialectError, use an auto increment column instead.
+func SequenceDefault(dialect Dialect, sequence string) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordSQL(fmt.Sprintf("DEFAULT nextval(%s)", literal(sequence)))
+	case SQLServer:
+		return keywordSQL("DEFAULT NEXT VALUE FOR " + sequence)
+	case Oracle:
+		return keywordSQL(fmt.Sprintf("DEFAULT %s.NEXTVAL", sequence))
+	default:
+		return missingDialect(dialect, "SequenceDefault")
+	}
+}
@@ -0,0 +1,349 @@
+package esperanto_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+func TestResetAutoIncrement(t *testing.T) {
+	t.Parallel()
+
+	reset := func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ResetAutoIncrement(dialect, "t", "id")
+	}
+
+	testSnapshot(t, reset, map[esperanto.Dialect]string{
+		esperanto.Postgres: "SELECT setval(pg_get_serial_sequence($1, $2), 1, false)",
+		esperanto.MySQL:    "ALTER TABLE t AUTO_INCREMENT = 1",
+		esperanto.Sqlite:   "DELETE FROM sqlite_sequence WHERE name = ?",
+		esperanto.SQLServer: "IF (SELECT last_value FROM sys.identity_columns WHERE object_id = OBJECT_ID(N't')) " +
+			"IS NULL DBCC CHECKIDENT (N't', RESEED, 1) ELSE DBCC CHECKIDENT (N't', RESEED, 0)",
+		esperanto.Oracle: "ALTER TABLE t MODIFY id GENERATED BY DEFAULT AS IDENTITY (START WITH 1)",
+	})
+
+	ctx := context.Background()
+
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)",
+		"INSERT INTO t (name) VALUES ('a'), ('b')",
+		"DELETE FROM t")
+
+	if err := db.Exec(ctx, reset(esperanto.Sqlite)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Exec(ctx, superbasic.SQL("INSERT INTO t (name) VALUES ('c')")); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := queryInts(t, db, "SELECT id FROM t"); !reflect.DeepEqual(ids, []int64{1}) {
+		t.Errorf("got %v, want [1]", ids)
+	}
+}
+
+func TestForeignKey(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ForeignKey(dialect, []string{"user_id"}, "users", []string{"id"}, esperanto.Cascade, "")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE",
+		esperanto.MySQL:     "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE",
+		esperanto.Sqlite:    "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE",
+		esperanto.SQLServer: "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE",
+		esperanto.Oracle:    "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ForeignKey(dialect, []string{"user_id"}, "users", []string{"id"}, esperanto.SetNull, esperanto.Cascade)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL ON UPDATE CASCADE",
+		esperanto.MySQL:     "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL ON UPDATE CASCADE",
+		esperanto.Sqlite:    "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL ON UPDATE CASCADE",
+		esperanto.SQLServer: "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL ON UPDATE CASCADE",
+		esperanto.Oracle:    fails,
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ForeignKey(dialect, []string{"user_id"}, "users", []string{"id"}, esperanto.SetDefault, "")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET DEFAULT",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET DEFAULT",
+		esperanto.SQLServer: "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET DEFAULT",
+		esperanto.Oracle:    fails,
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ForeignKey(dialect, []string{"user_id"}, "users", []string{"id"}, esperanto.Restrict, esperanto.NoAction)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT ON UPDATE NO ACTION",
+		esperanto.MySQL:     "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT ON UPDATE NO ACTION",
+		esperanto.Sqlite:    "FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT ON UPDATE NO ACTION",
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestCreateTableIfNotExists(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateTableIfNotExists(dialect, "users", superbasic.SQL("id INTEGER"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CREATE TABLE IF NOT EXISTS users (\n\tid INTEGER\n)",
+		esperanto.MySQL:     "CREATE TABLE IF NOT EXISTS users (\n\tid INTEGER\n)",
+		esperanto.Sqlite:    "CREATE TABLE IF NOT EXISTS users (\n\tid INTEGER\n)",
+		esperanto.SQLServer: "IF OBJECT_ID(N'users', N'U') IS NULL CREATE TABLE users (\n\tid INTEGER\n)",
+		esperanto.Oracle: "BEGIN\n\tEXECUTE IMMEDIATE 'CREATE TABLE users (\n\tid INTEGER\n)';\nEXCEPTION\n\tWHEN OTHERS " +
+			"THEN\n\t\tIF SQLCODE != -955 THEN\n\t\t\tRAISE;\n\t\tEND IF;\nEND;",
+	})
+}
+
+func TestDropTableIfExists(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DropTableIfExists(dialect, "users")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DROP TABLE IF EXISTS users",
+		esperanto.MySQL:     "DROP TABLE IF EXISTS users",
+		esperanto.Sqlite:    "DROP TABLE IF EXISTS users",
+		esperanto.SQLServer: "IF OBJECT_ID(N'users', N'U') IS NOT NULL DROP TABLE users",
+		esperanto.Oracle: "BEGIN\n\tEXECUTE IMMEDIATE 'DROP TABLE users';\nEXCEPTION\n\tWHEN OTHERS THEN\n\t\tIF SQLCODE " +
+			"!= -942 THEN\n\t\t\tRAISE;\n\t\tEND IF;\nEND;",
+	})
+}
+
+func TestComment(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Comment(dialect, esperanto.CommentTarget{Table: "users"}, "the users")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "COMMENT ON TABLE users IS 'the users'",
+		esperanto.MySQL:    "ALTER TABLE users COMMENT = 'the users'",
+		esperanto.Sqlite:   fails,
+		esperanto.SQLServer: "EXEC sp_addextendedproperty @name = N'MS_Description', @value = N'the users', @level0type = " +
+			"N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = N'users'",
+		esperanto.Oracle: "COMMENT ON TABLE users IS 'the users'",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Comment(dialect, esperanto.CommentTarget{Table: "users", Column: "name"}, "it's the name")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "COMMENT ON COLUMN users.name IS 'it''s the name'",
+		esperanto.MySQL:    "COMMENT 'it''s the name'",
+		esperanto.Sqlite:   fails,
+		esperanto.SQLServer: "EXEC sp_addextendedproperty @name = N'MS_Description', @value = N'it''s the name', " +
+			"@level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', @level1name = " +
+			"N'users', @level2type = N'COLUMN', @level2name = N'name'",
+		esperanto.Oracle: "COMMENT ON COLUMN users.name IS 'it''s the name'",
+	})
+}
+
+func TestGeneratedColumn(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.GeneratedColumn(dialect, "total", "INTEGER", superbasic.SQL("price * quantity"), true)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "total INTEGER GENERATED ALWAYS AS (price * quantity) STORED",
+		esperanto.MySQL:     "total INTEGER GENERATED ALWAYS AS (price * quantity) STORED",
+		esperanto.Sqlite:    "total INTEGER GENERATED ALWAYS AS (price * quantity) STORED",
+		esperanto.SQLServer: "total AS (price * quantity) PERSISTED",
+		esperanto.Oracle:    fails,
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.GeneratedColumn(dialect, "total", "INTEGER", superbasic.SQL("price * quantity"), false)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "total INTEGER GENERATED ALWAYS AS (price * quantity) VIRTUAL",
+		esperanto.MySQL:     "total INTEGER GENERATED ALWAYS AS (price * quantity) VIRTUAL",
+		esperanto.Sqlite:    "total INTEGER GENERATED ALWAYS AS (price * quantity) VIRTUAL",
+		esperanto.SQLServer: "total AS (price * quantity)",
+		esperanto.Oracle:    "total INTEGER GENERATED ALWAYS AS (price * quantity) VIRTUAL",
+	})
+}
+
+func TestBoolDefault(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BoolDefault(dialect, true)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DEFAULT TRUE",
+		esperanto.MySQL:     "DEFAULT TRUE",
+		esperanto.Sqlite:    "DEFAULT TRUE",
+		esperanto.SQLServer: "DEFAULT 1",
+		esperanto.Oracle:    "DEFAULT 1",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BoolDefault(dialect, false)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DEFAULT FALSE",
+		esperanto.MySQL:     "DEFAULT FALSE",
+		esperanto.Sqlite:    "DEFAULT FALSE",
+		esperanto.SQLServer: "DEFAULT 0",
+		esperanto.Oracle:    "DEFAULT 0",
+	})
+}
+
+func TestCreateTableAs(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateTableAs(dialect, "archive", superbasic.SQL("SELECT * FROM orders WHERE year < 2020"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CREATE TABLE archive AS SELECT * FROM orders WHERE year < 2020",
+		esperanto.MySQL:     "CREATE TABLE archive AS SELECT * FROM orders WHERE year < 2020",
+		esperanto.Sqlite:    "CREATE TABLE archive AS SELECT * FROM orders WHERE year < 2020",
+		esperanto.SQLServer: "SELECT * INTO archive FROM (SELECT * FROM orders WHERE year < 2020) AS t",
+		esperanto.Oracle:    "CREATE TABLE archive AS SELECT * FROM orders WHERE year < 2020",
+	})
+}
+
+func TestCreateTempTable(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateTempTable(dialect, "ids", superbasic.SQL("id INTEGER"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CREATE TEMP TABLE ids (\n\tid INTEGER\n)",
+		esperanto.MySQL:     "CREATE TEMPORARY TABLE ids (\n\tid INTEGER\n)",
+		esperanto.Sqlite:    "CREATE TEMP TABLE ids (\n\tid INTEGER\n)",
+		esperanto.SQLServer: "CREATE TABLE #ids (\n\tid INTEGER\n)",
+		esperanto.Oracle:    "CREATE GLOBAL TEMPORARY TABLE ids (\n\tid INTEGER\n) ON COMMIT PRESERVE ROWS",
+	})
+
+	// a name with # is kept
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateTempTable(dialect, "#ids", superbasic.SQL("id INTEGER"))
+	}, map[esperanto.Dialect]string{
+		esperanto.SQLServer: "CREATE TABLE #ids (\n\tid INTEGER\n)",
+	})
+}
+
+func TestCreateIndex(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateIndex(dialect, "users_email", "users", []string{"email"}, esperanto.IndexOptions{Unique: true})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CREATE UNIQUE INDEX users_email ON users (email)",
+		esperanto.MySQL:     "CREATE UNIQUE INDEX users_email ON users (email)",
+		esperanto.Sqlite:    "CREATE UNIQUE INDEX users_email ON users (email)",
+		esperanto.SQLServer: "CREATE UNIQUE INDEX users_email ON users (email)",
+		esperanto.Oracle:    "CREATE UNIQUE INDEX users_email ON users (email)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateIndex(dialect, "users_email", "users", []string{"email"}, esperanto.IndexOptions{IfNotExists: true})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "CREATE INDEX IF NOT EXISTS users_email ON users (email)",
+		esperanto.MySQL:    fails,
+		esperanto.Sqlite:   "CREATE INDEX IF NOT EXISTS users_email ON users (email)",
+		esperanto.SQLServer: "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'users_email' AND object_id = " +
+			"OBJECT_ID(N'users')) CREATE INDEX users_email ON users (email)",
+		esperanto.Oracle: "BEGIN\n\tEXECUTE IMMEDIATE 'CREATE INDEX users_email ON users (email)';\nEXCEPTION\n\tWHEN " +
+			"OTHERS THEN\n\t\tIF SQLCODE != -955 THEN\n\t\t\tRAISE;\n\t\tEND IF;\nEND;",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CreateIndex(dialect, "users_email", "users", []string{"email"}, esperanto.IndexOptions{
+			Include: []string{"name"},
+			Where:   superbasic.SQL("deleted_at IS NULL"),
+		})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CREATE INDEX users_email ON users (email) INCLUDE (name) WHERE deleted_at IS NULL",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "CREATE INDEX users_email ON users (email) INCLUDE (name) WHERE deleted_at IS NULL",
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestAlterColumnType(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.AlterColumnType(dialect, "users", "name", "VARCHAR(200)", nil)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(200)",
+		esperanto.MySQL:     "ALTER TABLE users MODIFY COLUMN name VARCHAR(200)",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "ALTER TABLE users ALTER COLUMN name VARCHAR(200)",
+		esperanto.Oracle:    "ALTER TABLE users MODIFY (name VARCHAR(200))",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.AlterColumnType(dialect, "users", "name", "VARCHAR(200)", superbasic.SQL("LEFT(name, 200)"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(200) USING LEFT(name, 200)",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestRenameTable(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.RenameTable(dialect, "users", "accounts")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ALTER TABLE users RENAME TO accounts",
+		esperanto.MySQL:     "ALTER TABLE users RENAME TO accounts",
+		esperanto.Sqlite:    "ALTER TABLE users RENAME TO accounts",
+		esperanto.SQLServer: "EXEC sp_rename N'users', N'accounts'",
+		esperanto.Oracle:    "ALTER TABLE users RENAME TO accounts",
+	})
+}
+
+func TestRenameColumn(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.RenameColumn(dialect, "users", "name", "full_name")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ALTER TABLE users RENAME COLUMN name TO full_name",
+		esperanto.MySQL:     "ALTER TABLE users RENAME COLUMN name TO full_name",
+		esperanto.Sqlite:    "ALTER TABLE users RENAME COLUMN name TO full_name",
+		esperanto.SQLServer: "EXEC sp_rename N'users.name', N'full_name', N'COLUMN'",
+		esperanto.Oracle:    "ALTER TABLE users RENAME COLUMN name TO full_name",
+	})
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Check(dialect, "age_positive", superbasic.SQL("age > 0"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CONSTRAINT age_positive CHECK (age > 0)",
+		esperanto.MySQL:     "CONSTRAINT age_positive CHECK (age > 0)",
+		esperanto.Sqlite:    "CONSTRAINT age_positive CHECK (age > 0)",
+		esperanto.SQLServer: "CONSTRAINT age_positive CHECK (age > 0)",
+		esperanto.Oracle:    "CONSTRAINT age_positive CHECK (age > 0)",
+	})
+}
+
+func TestSequenceDefault(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.SequenceDefault(dialect, "users_seq")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DEFAULT nextval('users_seq')",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "DEFAULT NEXT VALUE FOR users_seq",
+		esperanto.Oracle:    "DEFAULT users_seq.NEXTVAL",
+	})
+}
@@ -4,7 +4,11 @@ package esperanto
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/wroge/scan"
 	"github.com/wroge/superbasic"
@@ -21,6 +25,102 @@ const (
 	SQLServer Dialect = "sqlserver"
 )
 
+// Dialects are the dialects supported by the builders of this package.
+var Dialects = []Dialect{MySQL, Sqlite, Postgres, Oracle, SQLServer}
+
+// Placeholder returns the default placeholder of a dialect to be used with superbasic.Finalize.
+func (d Dialect) Placeholder() string {
+	switch d {
+	case Postgres:
+		return "$%d"
+	case SQLServer:
+		return "@p%d"
+	case Oracle:
+		return ":%d"
+	default:
+		return "?"
+	}
+}
+
+// PlaceholderFunc renders the placeholder of the argument at index, starting at 1.
+// name is the name of a sql.NamedArg argument or empty.
+type PlaceholderFunc func(index int, name string) string
+
+// Placeholders returns a PlaceholderFunc for a static placeholder like ? or a positional placeholder like $%d.
+func Placeholders(placeholder string) PlaceholderFunc {
+	positional := strings.Contains(placeholder, "%d")
+
+	return func(index int, _ string) string {
+		if positional {
+			return fmt.Sprintf(placeholder, index)
+		}
+
+		return placeholder
+	}
+}
+
+// Finalize is superbasic.Finalize with a PlaceholderFunc. Escaped placeholders (??) are replaced by ?.
+func Finalize(placeholder PlaceholderFunc, expression superbasic.Expression) (string, []any, error) {
+	if expression == nil {
+		return "", nil, superbasic.ExpressionError{}
+	}
+
+	text, args, err := expression.ToSQL()
+	if err != nil {
+		return "", nil, err
+	}
+
+	builder := &strings.Builder{}
+	count := 0
+
+	for {
+		index := strings.IndexByte(text, '?')
+		if index < 0 {
+			builder.WriteString(text)
+
+			break
+		}
+
+		builder.WriteString(text[:index])
+
+		if index < len(text)-1 && text[index+1] == '?' {
+			builder.WriteString("?")
+			text = text[index+2:]
+
+			continue
+		}
+
+		var name string
+
+		if count < len(args) {
+			if named, ok := args[count].(sql.NamedArg); ok {
+				name = named.Name
+			}
+		}
+
+		count++
+
+		builder.WriteString(placeholder(count, name))
+		text = text[index+1:]
+	}
+
+	if count != len(args) {
+		return "", nil, superbasic.NumberOfArgumentsError{SQL: builder.String(), Placeholders: count, Arguments: len(args)}
+	}
+
+	return builder.String(), args, nil
+}
+
+func finalize(placeholder string, placeholderFunc PlaceholderFunc,
+	expression superbasic.Expression) (string, []any, error) {
+	if placeholderFunc != nil {
+		return Finalize(placeholderFunc, expression)
+	}
+
+	// superbasic.Finalize keeps ?? for the ? placeholder, escaped literals must reach the database as ?.
+	return Finalize(Placeholders(placeholder), expression)
+}
+
 type Queryable[MODEL, OPTIONS any] func(dialect Dialect, options OPTIONS) (superbasic.Expression, []scan.Column[MODEL])
 
 type QueryExecutable[MODEL, OPTIONS any] func(dialect Dialect, options OPTIONS, models []MODEL) superbasic.Expression
@@ -29,6 +129,33 @@ type QueryOneExecutable[MODEL, OPTIONS any] func(dialect Dialect, options OPTION
 
 type Executable func(dialect Dialect) superbasic.Expression
 
+// Snapshot finalizes executable for all Dialects with their default placeholders, e.g. for golden tests.
+// It takes an Executable instead of a single Expression, because an Expression is already built for one
+// Dialect and the builders of this package render different SQL per Dialect.
+// The errors, like a MissingDialectError, are returned by dialect. Dialects skipped by Only or Unless are left out.
+func Snapshot(executable Executable) (map[Dialect]string, map[Dialect]error) {
+	snapshots := map[Dialect]string{}
+	errs := map[Dialect]error{}
+
+	for _, dialect := range Dialects {
+		expression := executable(dialect)
+		if skipped(expression) {
+			continue
+		}
+
+		sql, _, err := Finalize(Placeholders(dialect.Placeholder()), expression)
+		if err != nil {
+			errs[dialect] = err
+
+			continue
+		}
+
+		snapshots[dialect] = sql
+	}
+
+	return snapshots, errs
+}
+
 func Exec(ctx context.Context, db DB, dialect Dialect, executables ...Executable) error {
 	txn, err := db.Begin(ctx)
 	if err != nil {
@@ -36,7 +163,12 @@ func Exec(ctx context.Context, db DB, dialect Dialect, executables ...Executable
 	}
 
 	for _, exec := range executables {
-		err = txn.Exec(ctx, exec(dialect))
+		expression := exec(dialect)
+		if skipped(expression) {
+			continue
+		}
+
+		err = txn.Exec(ctx, expression)
 		if err != nil {
 			return txn.Rollback(ctx, err)
 		}
@@ -45,12 +177,122 @@ func Exec(ctx context.Context, db DB, dialect Dialect, executables ...Executable
 	return txn.Commit(ctx)
 }
 
+// skip is returned by Only and Unless for the dialects that skip an executable.
+type skip struct{}
+
+func (skip) ToSQL() (string, []any, error) {
+	return "", nil, errors.New("wroge/esperanto error: executable is skipped for this dialect")
+}
+
+func skipped(expression superbasic.Expression) bool {
+	_, ok := expression.(skip)
+
+	return ok
+}
+
+// Only runs exec only for dialect and skips it in Exec for other dialects.
+func Only(dialect Dialect, exec Executable) Executable {
+	return func(d Dialect) superbasic.Expression {
+		if d != dialect {
+			return skip{}
+		}
+
+		return exec(d)
+	}
+}
+
+// Unless skips exec in Exec for dialect and runs it for other dialects.
+func Unless(dialect Dialect, exec Executable) Executable {
+	return func(d Dialect) superbasic.Expression {
+		if d == dialect {
+			return skip{}
+		}
+
+		return exec(d)
+	}
+}
+
+// ErrTooManyRows is returned by Query if the result set exceeds the limit set by WithMaxRows.
+var ErrTooManyRows = errors.New("wroge/esperanto error: too many rows")
+
+// ErrNoColumns is returned if the columns of a result set are needed, but the rows don't report them.
+var ErrNoColumns = errors.New("wroge/esperanto error: rows do not report their columns")
+
+// QueryOption configures a single call of Query.
+type QueryOption func(*queryConfig)
+
+type queryConfig struct {
+	maxRows       int
+	truncate      bool
+	cancelOnError bool
+	columnMatch   columnMatch
+}
+
+type columnMatch int
+
+const (
+	anyColumns columnMatch = iota
+	strictColumns
+	partialColumns
+)
+
+// WithMaxRows stops scanning after n rows and returns ErrTooManyRows if the result set has more rows.
+func WithMaxRows(n int) QueryOption {
+	return func(config *queryConfig) {
+		config.maxRows = n
+	}
+}
+
+// WithTruncate returns the first rows up to the limit of WithMaxRows instead of ErrTooManyRows.
+func WithTruncate() QueryOption {
+	return func(config *queryConfig) {
+		config.truncate = true
+	}
+}
+
+// WithCancelOnError cancels the context of the query if scanning stops early, e.g. because of an error,
+// so that the server can abort the query instead of the driver draining the remaining rows.
+func WithCancelOnError() QueryOption {
+	return func(config *queryConfig) {
+		config.cancelOnError = true
+	}
+}
+
+// WithStrictColumns returns a ColumnCountError if the result set has not exactly as many columns as scanned.
+func WithStrictColumns() QueryOption {
+	return func(config *queryConfig) {
+		config.columnMatch = strictColumns
+	}
+}
+
+// WithPartialColumns ignores the result columns following the scanned columns,
+// e.g. to scan only the first columns of SELECT *.
+func WithPartialColumns() QueryOption {
+	return func(config *queryConfig) {
+		config.columnMatch = partialColumns
+	}
+}
+
 func Query[MODEL, OPTIONS any](
 	ctx context.Context,
 	db DB,
 	dialect Dialect,
 	queryable Queryable[MODEL, OPTIONS],
-	options OPTIONS) ([]MODEL, error) {
+	options OPTIONS,
+	queryOptions ...QueryOption) ([]MODEL, error) {
+	config := queryConfig{maxRows: -1}
+
+	for _, option := range queryOptions {
+		option(&config)
+	}
+
+	var cancel context.CancelFunc
+
+	if config.cancelOnError {
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+	}
+
 	expression, columns := queryable(dialect, options)
 
 	rows, err := db.Query(ctx, expression)
@@ -58,18 +300,316 @@ func Query[MODEL, OPTIONS any](
 		return nil, err
 	}
 
+	if config.columnMatch != anyColumns {
+		rows, err = matchColumns(rows, len(columns), config.columnMatch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cancel != nil {
+		rows = &cancelRows{Rows: rows, cancel: cancel}
+	}
+
+	if config.maxRows >= 0 {
+		rows = &limitRows{Rows: rows, limit: config.maxRows, truncate: config.truncate}
+	}
+
 	return scan.All(rows, columns...)
 }
 
+// QueryMore queries at most limit models and reports whether there are more. The query of queryable must
+// not be limited, as a Paginate clause for limit+1 rows is appended. SQLServer needs an ORDER BY clause.
+func QueryMore[MODEL, OPTIONS any](
+	ctx context.Context,
+	db DB,
+	dialect Dialect,
+	queryable Queryable[MODEL, OPTIONS],
+	options OPTIONS,
+	limit int) ([]MODEL, bool, error) {
+	if limit < 0 {
+		return nil, false, fmt.Errorf("wroge/esperanto error: invalid limit %d", limit)
+	}
+
+	expression, columns := queryable(dialect, options)
+
+	rows, err := db.Query(ctx, superbasic.Join(" ", expression, Paginate(dialect, limit+1, 0, PaginateOptions{})))
+	if err != nil {
+		return nil, false, err
+	}
+
+	models, err := scan.All(rows, columns...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(models) > limit {
+		return models[:limit], true, nil
+	}
+
+	return models, false, nil
+}
+
+// limitRows ends the iteration after limit rows and closes the underlying rows early.
+type limitRows struct {
+	scan.Rows
+	limit    int
+	count    int
+	truncate bool
+	exceeded bool
+}
+
+func (r *limitRows) Next() bool {
+	if r.count >= r.limit {
+		if !r.truncate {
+			r.exceeded = r.Rows.Next()
+		}
+
+		return false
+	}
+
+	if !r.Rows.Next() {
+		return false
+	}
+
+	r.count++
+
+	return true
+}
+
+func (r *limitRows) Err() error {
+	if r.exceeded {
+		return ErrTooManyRows
+	}
+
+	return r.Rows.Err()
+}
+
+func (r *limitRows) Close() error {
+	return closeRows(r.Rows)
+}
+
+// ColumnCountError is returned by Query with WithStrictColumns or WithPartialColumns
+// if the result set has an unexpected number of columns.
+type ColumnCountError struct {
+	Expected int
+	Got      int
+}
+
+func (e ColumnCountError) Error() string {
+	return fmt.Sprintf("wroge/esperanto error: expected %d columns, got %d", e.Expected, e.Got)
+}
+
+// matchColumns compares the number of result columns with the number of scanned columns.
+// The rows must report their columns like *sql.Rows.
+func matchColumns(rows scan.Rows, expected int, match columnMatch) (scan.Rows, error) {
+	columnRows, ok := rows.(interface{ Columns() ([]string, error) })
+	if !ok {
+		_ = closeRows(rows)
+
+		return nil, ErrNoColumns
+	}
+
+	names, err := columnRows.Columns()
+	if err != nil {
+		_ = closeRows(rows)
+
+		return nil, err
+	}
+
+	got := len(names)
+
+	if got == expected {
+		return rows, nil
+	}
+
+	if match == partialColumns && got > expected {
+		return partialRows{Rows: rows, extra: got - expected}, nil
+	}
+
+	_ = closeRows(rows)
+
+	return nil, ColumnCountError{Expected: expected, Got: got}
+}
+
+// partialRows discards the trailing columns of each row.
+type partialRows struct {
+	scan.Rows
+	extra int
+}
+
+func (r partialRows) Scan(dest ...any) error {
+	for i := 0; i < r.extra; i++ {
+		dest = append(dest, new(any))
+	}
+
+	return r.Rows.Scan(dest...)
+}
+
+func (r partialRows) Close() error {
+	return closeRows(r.Rows)
+}
+
+// cancelRows cancels the query if it is closed before all rows are read.
+type cancelRows struct {
+	scan.Rows
+	cancel context.CancelFunc
+	done   bool
+}
+
+func (r *cancelRows) Next() bool {
+	if !r.Rows.Next() {
+		r.done = true
+
+		return false
+	}
+
+	return true
+}
+
+func (r *cancelRows) Close() error {
+	if !r.done {
+		r.cancel()
+	}
+
+	return closeRows(r.Rows)
+}
+
+func closeRows(rows scan.Rows) error {
+	switch r := rows.(type) {
+	case interface{ Close() }:
+		r.Close()
+	case interface{ Close() error }:
+		return r.Close()
+	}
+
+	return nil
+}
+
+// QueryOne scans the first row of the query. Of the QueryOptions, only WithStrictColumns
+// and WithPartialColumns apply.
 func QueryOne[MODEL, OPTIONS any](
 	ctx context.Context,
 	db DB,
 	dialect Dialect,
 	queryable Queryable[MODEL, OPTIONS],
-	options OPTIONS) (MODEL, error) {
+	options OPTIONS,
+	queryOptions ...QueryOption) (MODEL, error) {
+	config := queryConfig{maxRows: -1}
+
+	for _, option := range queryOptions {
+		option(&config)
+	}
+
 	expression, columns := queryable(dialect, options)
 
-	return scan.One(db.QueryRow(ctx, expression), columns...)
+	if config.columnMatch == anyColumns {
+		return scan.One(db.QueryRow(ctx, expression), columns...)
+	}
+
+	var model MODEL
+
+	rows, err := db.Query(ctx, expression)
+	if err != nil {
+		return model, err
+	}
+
+	rows, err = matchColumns(rows, len(columns), config.columnMatch)
+	if err != nil {
+		return model, err
+	}
+
+	return scan.One[MODEL](firstRow{Rows: rows}, columns...)
+}
+
+// firstRow scans the first of rows and closes them like *sql.Row.
+type firstRow struct {
+	scan.Rows
+}
+
+func (r firstRow) Scan(dest ...any) error {
+	if !r.Rows.Next() {
+		err := r.Rows.Err()
+		if err == nil {
+			err = sql.ErrNoRows
+		}
+
+		_ = closeRows(r.Rows)
+
+		return err
+	}
+
+	err := r.Rows.Scan(dest...)
+	if closeErr := closeRows(r.Rows); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// keysPerQuery is the chunk size of QueryByKeys. Oracle doesn't allow more than 1000 expressions in a list.
+const keysPerQuery = 1000
+
+// QueryByKeys selects all rows of table whose keyColumn is in keys and maps them by their key.
+// The columns must match the columns of table in order.
+// Large sets of keys are queried in chunks.
+func QueryByKeys[MODEL any, KEY comparable](
+	ctx context.Context,
+	db DB,
+	dialect Dialect,
+	table, keyColumn string,
+	keys []KEY,
+	columns []scan.Column[MODEL]) (map[KEY]MODEL, error) {
+	models := make(map[KEY]MODEL, len(keys))
+
+	keyedColumns := make([]scan.Column[keyed[KEY, MODEL]], 0, len(columns)+1)
+	keyedColumns = append(keyedColumns, scan.Any(func(each *keyed[KEY, MODEL], key KEY) { each.key = key }))
+
+	for _, column := range columns {
+		keyedColumns = append(keyedColumns, keyedColumn[KEY, MODEL]{column: column})
+	}
+
+	for start := 0; start < len(keys); start += keysPerQuery {
+		end := start + keysPerQuery
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		rows, err := db.Query(ctx, superbasic.Compile(fmt.Sprintf("SELECT %s, %s.* FROM %s WHERE ?", keyColumn, table, table),
+			In(keyColumn, keys[start:end])))
+		if err != nil {
+			return nil, err
+		}
+
+		all, err := scan.All(rows, keyedColumns...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, each := range all {
+			models[each.key] = each.model
+		}
+	}
+
+	return models, nil
+}
+
+type keyed[KEY, MODEL any] struct {
+	key   KEY
+	model MODEL
+}
+
+// keyedColumn scans a column of MODEL into a keyed MODEL.
+type keyedColumn[KEY, MODEL any] struct {
+	column scan.Column[MODEL]
+}
+
+func (c keyedColumn[KEY, MODEL]) Scan() any {
+	return c.column.Scan()
+}
+
+func (c keyedColumn[KEY, MODEL]) Set(each *keyed[KEY, MODEL]) error {
+	return c.column.Set(&each.model)
 }
 
 func QueryAndExec[MODEL, OPTIONS any](
@@ -141,6 +681,39 @@ func QueryAndExecOne[MODEL, OPTIONS any](
 	return model, txn.Commit(ctx)
 }
 
+// GetOrCreate runs insert, which returns the created row, e.g. INSERT ... ON CONFLICT DO NOTHING RETURNING ....
+// If insert returns no row, the existing row is queried by selectExisting in the same transaction.
+// The returned bool reports whether the row was created.
+func GetOrCreate[MODEL any](
+	ctx context.Context,
+	db DB,
+	dialect Dialect,
+	insert, selectExisting superbasic.Expression,
+	columns []scan.Column[MODEL]) (MODEL, bool, error) {
+	txn, err := db.Begin(ctx)
+	if err != nil {
+		var model MODEL
+
+		return model, false, err
+	}
+
+	model, err := scan.One(txn.QueryRow(ctx, insert), columns...)
+	if err == nil {
+		return model, true, txn.Commit(ctx)
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return model, false, txn.Rollback(ctx, err)
+	}
+
+	model, err = scan.One(txn.QueryRow(ctx, selectExisting), columns...)
+	if err != nil {
+		return model, false, txn.Rollback(ctx, err)
+	}
+
+	return model, false, txn.Commit(ctx)
+}
+
 type Tx interface {
 	Commit(ctx context.Context) error
 	Rollback(ctx context.Context, err error) error
@@ -159,7 +732,14 @@ type DB interface {
 
 type StdDB struct {
 	Placeholder string
-	DB          *sql.DB
+	// PlaceholderFunc replaces Placeholder if set.
+	PlaceholderFunc PlaceholderFunc
+	DB              *sql.DB
+	// DefaultTxOptions are used by Begin. BeginTx overrides them.
+	DefaultTxOptions *sql.TxOptions
+	// PrepareInTx prepares the statements of a transaction once per SQL and reuses them,
+	// e.g. for the executables of QueryAndExec that run for many models.
+	PrepareInTx bool
 }
 
 func (s StdDB) Close() error {
@@ -167,16 +747,26 @@ func (s StdDB) Close() error {
 }
 
 func (s StdDB) Begin(ctx context.Context) (Tx, error) {
-	tx, err := s.DB.BeginTx(ctx, nil)
+	return s.BeginTx(ctx, s.DefaultTxOptions)
+}
+
+func (s StdDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := s.DB.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return StdTx{Placeholder: s.Placeholder, Tx: tx}, nil
+	stdTx := StdTx{Placeholder: s.Placeholder, PlaceholderFunc: s.PlaceholderFunc, Tx: tx}
+
+	if s.PrepareInTx {
+		stdTx.statements = &statementCache{}
+	}
+
+	return stdTx, nil
 }
 
 func (s StdDB) Query(ctx context.Context, expression superbasic.Expression) (scan.Rows, error) {
-	sql, args, err := superbasic.Finalize(s.Placeholder, expression)
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +775,7 @@ func (s StdDB) Query(ctx context.Context, expression superbasic.Expression) (sca
 }
 
 func (s StdDB) QueryRow(ctx context.Context, expression superbasic.Expression) scan.Row {
-	sql, args, err := superbasic.Finalize(s.Placeholder, expression)
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
 	if err != nil {
 		return RowError{Err: err}
 	}
@@ -194,7 +784,7 @@ func (s StdDB) QueryRow(ctx context.Context, expression superbasic.Expression) s
 }
 
 func (s StdDB) Exec(ctx context.Context, expression superbasic.Expression) error {
-	sql, args, err := superbasic.Finalize(s.Placeholder, expression)
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
 	if err != nil {
 		return err
 	}
@@ -208,11 +798,54 @@ func (s StdDB) Exec(ctx context.Context, expression superbasic.Expression) error
 }
 
 type StdTx struct {
-	Placeholder string
-	Tx          *sql.Tx
+	Placeholder     string
+	PlaceholderFunc PlaceholderFunc
+	Tx              *sql.Tx
+	statements      *statementCache
+}
+
+// statementCache holds the prepared statements of a transaction by SQL.
+// The statements are closed by the commit or rollback of the transaction.
+type statementCache struct {
+	mutex      sync.Mutex
+	statements map[string]*sql.Stmt
+}
+
+func (c *statementCache) prepare(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if stmt, ok := c.statements[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.statements == nil {
+		c.statements = map[string]*sql.Stmt{}
+	}
+
+	c.statements[query] = stmt
+
+	return stmt, nil
+}
+
+func (c *statementCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	c.statements = nil
+	c.mutex.Unlock()
 }
 
 func (s StdTx) Commit(ctx context.Context) error {
+	defer s.statements.clear()
+
 	return s.Tx.Commit()
 }
 
@@ -230,6 +863,8 @@ func (re RollbackError) Unwrap() error {
 }
 
 func (s StdTx) Rollback(ctx context.Context, err error) error {
+	defer s.statements.clear()
+
 	if rollbackErr := s.Tx.Rollback(); rollbackErr != nil {
 		return RollbackError{
 			Err:  rollbackErr,
@@ -241,30 +876,135 @@ func (s StdTx) Rollback(ctx context.Context, err error) error {
 }
 
 func (s StdTx) Query(ctx context.Context, expression superbasic.Expression) (scan.Rows, error) {
-	sql, args, err := superbasic.Finalize(s.Placeholder, expression)
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
 	if err != nil {
 		return nil, err
 	}
 
+	if s.statements != nil {
+		stmt, err := s.statements.prepare(ctx, s.Tx, sql)
+		if err != nil {
+			return nil, err
+		}
+
+		return stmt.QueryContext(ctx, args...)
+	}
+
 	return s.Tx.QueryContext(ctx, sql, args...)
 }
 
 func (s StdTx) QueryRow(ctx context.Context, expression superbasic.Expression) scan.Row {
-	sql, args, err := superbasic.Finalize(s.Placeholder, expression)
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
 	if err != nil {
 		return RowError{Err: err}
 	}
 
+	if s.statements != nil {
+		stmt, err := s.statements.prepare(ctx, s.Tx, sql)
+		if err != nil {
+			return RowError{Err: err}
+		}
+
+		return stmt.QueryRowContext(ctx, args...)
+	}
+
 	return s.Tx.QueryRowContext(ctx, sql, args...)
 }
 
 func (s StdTx) Exec(ctx context.Context, expression superbasic.Expression) error {
-	sql, args, err := superbasic.Finalize(s.Placeholder, expression)
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
+	if err != nil {
+		return err
+	}
+
+	if s.statements != nil {
+		stmt, prepareErr := s.statements.prepare(ctx, s.Tx, sql)
+		if prepareErr != nil {
+			return prepareErr
+		}
+
+		_, err = stmt.ExecContext(ctx, args...)
+	} else {
+		_, err = s.Tx.ExecContext(ctx, sql, args...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Conn pins a connection of the pool, e.g. for session state like the locks of AcquireAdvisoryLock on MySQL.
+func (s StdDB) Conn(ctx context.Context) (*StdConn, error) {
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StdConn{Placeholder: s.Placeholder, PlaceholderFunc: s.PlaceholderFunc, Conn: conn}, nil
+}
+
+// StdConn is a pinned connection of a StdDB. Close resets the session state set by this package,
+// e.g. by SetTimeZone, and discards the connection if that fails, so that no state is left in the pool.
+type StdConn struct {
+	Placeholder     string
+	PlaceholderFunc PlaceholderFunc
+	Conn            *sql.Conn
+	resets          []superbasic.Expression
+}
+
+// onClose adds a statement that resets session state when the connection is closed.
+func (s *StdConn) onClose(reset superbasic.Expression) {
+	s.resets = append(s.resets, reset)
+}
+
+func (s *StdConn) Close() error {
+	var err error
+
+	for _, reset := range s.resets {
+		// the state must be reset, even if the context of the caller is done
+		if err = s.Exec(context.Background(), reset); err != nil {
+			break
+		}
+	}
+
+	s.resets = nil
+
+	if err != nil {
+		_ = s.Conn.Raw(func(any) error { return driver.ErrBadConn })
+
+		return err
+	}
+
+	return s.Conn.Close()
+}
+
+func (s *StdConn) Query(ctx context.Context, expression superbasic.Expression) (scan.Rows, error) {
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Conn.QueryContext(ctx, sql, args...)
+}
+
+func (s *StdConn) QueryRow(ctx context.Context, expression superbasic.Expression) scan.Row {
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
+	if err != nil {
+		return RowError{Err: err}
+	}
+
+	return s.Conn.QueryRowContext(ctx, sql, args...)
+}
+
+func (s *StdConn) Exec(ctx context.Context, expression superbasic.Expression) error {
+	sql, args, err := finalize(s.Placeholder, s.PlaceholderFunc, expression)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.Tx.ExecContext(ctx, sql, args...)
+	_, err = s.Conn.ExecContext(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -272,6 +1012,20 @@ func (s StdTx) Exec(ctx context.Context, expression superbasic.Expression) error
 	return nil
 }
 
+// MissingDialectError is returned if an expression is not supported by a dialect.
+type MissingDialectError struct {
+	Dialect Dialect
+	Name    string
+}
+
+func (e MissingDialectError) Error() string {
+	return fmt.Sprintf("wroge/esperanto error: %s is not supported by dialect '%s'", e.Name, e.Dialect)
+}
+
+func missingDialect(dialect Dialect, name string) superbasic.Expression {
+	return superbasic.Raw{Err: MissingDialectError{Dialect: dialect, Name: name}}
+}
+
 type RowError struct {
 	Err error
 }
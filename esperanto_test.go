@@ -0,0 +1,439 @@
+package esperanto_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+
+	_ "modernc.org/sqlite"
+)
+
+// openSqlite opens an in-memory sqlite database and runs the statements.
+func openSqlite(t *testing.T, statements ...string) esperanto.StdDB {
+	t.Helper()
+
+	stdDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every connection has its own in-memory database
+	stdDB.SetMaxOpenConns(1)
+
+	t.Cleanup(func() { _ = stdDB.Close() })
+
+	db := esperanto.StdDB{Placeholder: "?", DB: stdDB}
+
+	for _, statement := range statements {
+		if err = db.Exec(context.Background(), superbasic.SQL(statement)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+// queryInts returns the first column of the rows of query.
+func queryInts(t *testing.T, db esperanto.DB, query string) []int64 {
+	t.Helper()
+
+	rows, err := db.Query(context.Background(), superbasic.SQL(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ints, err := scan.All[int64](rows, scan.Any(func(i *int64, value int64) { *i = value }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ints
+}
+
+func TestOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	create := func(esperanto.Dialect) superbasic.Expression {
+		return superbasic.SQL("CREATE TABLE t (id INTEGER)")
+	}
+
+	if err := esperanto.Exec(ctx, db, esperanto.Sqlite, esperanto.Only(esperanto.MySQL, create),
+		esperanto.Unless(esperanto.MySQL, create)); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, errs := esperanto.Snapshot(esperanto.Only(esperanto.MySQL, create))
+	if len(snapshots) != 1 || len(errs) != 0 {
+		t.Errorf("got %v %v, want only MySQL", snapshots, errs)
+	}
+
+	// a nil expression is an error and not skipped
+	err := esperanto.Exec(ctx, db, esperanto.Sqlite, func(esperanto.Dialect) superbasic.Expression { return nil })
+	if !errors.As(err, &superbasic.ExpressionError{}) {
+		t.Errorf("got %v, want an ExpressionError", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	insert := func(dialect esperanto.Dialect) superbasic.Expression {
+		switch dialect {
+		case esperanto.Postgres, esperanto.Sqlite:
+			return superbasic.Compile("INSERT INTO authors (name) VALUES ? RETURNING id, name",
+				superbasic.Join(", ", superbasic.Values{"Jim"}, superbasic.Values{"Tim"}))
+		default:
+			return superbasic.Raw{Err: esperanto.MissingDialectError{Dialect: dialect, Name: "AuthorInsert"}}
+		}
+	}
+
+	snapshots, errs := esperanto.Snapshot(insert)
+
+	want := map[esperanto.Dialect]string{
+		esperanto.Postgres: "INSERT INTO authors (name) VALUES ($1), ($2) RETURNING id, name",
+		esperanto.Sqlite:   "INSERT INTO authors (name) VALUES (?), (?) RETURNING id, name",
+	}
+
+	if !reflect.DeepEqual(snapshots, want) {
+		t.Errorf("got %v, want %v", snapshots, want)
+	}
+
+	for _, dialect := range []esperanto.Dialect{esperanto.MySQL, esperanto.SQLServer, esperanto.Oracle} {
+		if !errors.As(errs[dialect], &esperanto.MissingDialectError{}) {
+			t.Errorf("got %v for %s, want a MissingDialectError", errs[dialect], dialect)
+		}
+	}
+}
+
+func TestQueryOne(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER, name TEXT)",
+		"INSERT INTO t (id, name) VALUES (1, 'a')")
+
+	queryable := func(_ esperanto.Dialect, id int64) (superbasic.Expression, []scan.Column[int64]) {
+		return superbasic.SQL("SELECT * FROM t WHERE id = ?", id),
+			[]scan.Column[int64]{scan.Any(func(i *int64, value int64) { *i = value })}
+	}
+
+	_, err := esperanto.QueryOne[int64, int64](ctx, db, esperanto.Sqlite, queryable, 1, esperanto.WithStrictColumns())
+	if !errors.As(err, &esperanto.ColumnCountError{}) {
+		t.Errorf("got %v, want a ColumnCountError", err)
+	}
+
+	id, err := esperanto.QueryOne[int64, int64](ctx, db, esperanto.Sqlite, queryable, 1, esperanto.WithPartialColumns())
+	if err != nil || id != 1 {
+		t.Errorf("got %d %v, want 1", id, err)
+	}
+
+	_, err = esperanto.QueryOne[int64, int64](ctx, db, esperanto.Sqlite, queryable, 2, esperanto.WithPartialColumns())
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func BenchmarkPrepareInTx(b *testing.B) {
+	for _, prepare := range []bool{false, true} {
+		b.Run(map[bool]string{false: "unprepared", true: "prepared"}[prepare], func(b *testing.B) {
+			stdDB, err := sql.Open("sqlite", ":memory:")
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			defer stdDB.Close()
+
+			stdDB.SetMaxOpenConns(1)
+
+			ctx := context.Background()
+			db := esperanto.StdDB{Placeholder: "?", DB: stdDB, PrepareInTx: prepare}
+
+			if err = db.Exec(ctx, superbasic.SQL("CREATE TABLE t (id INTEGER, name TEXT)")); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				tx, err := db.Begin(ctx)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				for j := 0; j < 100; j++ {
+					if err = tx.Exec(ctx, superbasic.SQL("INSERT INTO t (id, name) VALUES (?, ?)", j, "name")); err != nil {
+						b.Fatal(tx.Rollback(ctx, err))
+					}
+				}
+
+				if err = tx.Rollback(ctx, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// selectIDs queries the ids of table t in order.
+func selectIDs(_ esperanto.Dialect, _ struct{}) (superbasic.Expression, []scan.Column[int64]) {
+	return superbasic.SQL("SELECT id FROM t ORDER BY id"),
+		[]scan.Column[int64]{scan.Any(func(i *int64, value int64) { *i = value })}
+}
+
+func TestWithMaxRows(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER)",
+		"INSERT INTO t (id) VALUES (1), (2), (3)")
+
+	_, err := esperanto.Query[int64, struct{}](ctx, db, esperanto.Sqlite, selectIDs, struct{}{}, esperanto.WithMaxRows(2))
+	if !errors.Is(err, esperanto.ErrTooManyRows) {
+		t.Errorf("got %v, want ErrTooManyRows", err)
+	}
+
+	ids, err := esperanto.Query[int64, struct{}](ctx, db, esperanto.Sqlite, selectIDs, struct{}{},
+		esperanto.WithMaxRows(2), esperanto.WithTruncate())
+	if err != nil || !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("got %v %v, want [1 2]", ids, err)
+	}
+
+	ids, err = esperanto.Query[int64, struct{}](ctx, db, esperanto.Sqlite, selectIDs, struct{}{}, esperanto.WithMaxRows(3))
+	if err != nil || !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("got %v %v, want [1 2 3]", ids, err)
+	}
+}
+
+// optionsConnector opens sqlite connections that record the options of their transactions.
+type optionsConnector struct {
+	driver  driver.Driver
+	options []driver.TxOptions
+}
+
+func (c *optionsConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	return optionsConn{Conn: conn, connector: c}, nil
+}
+
+func (c *optionsConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type optionsConn struct {
+	driver.Conn
+	connector *optionsConnector
+}
+
+func (c optionsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.connector.options = append(c.connector.options, opts)
+
+	beginner, ok := c.Conn.(driver.ConnBeginTx)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	return beginner.BeginTx(ctx, opts)
+}
+
+func TestDefaultTxOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	connector := &optionsConnector{driver: openSqlite(t).DB.Driver()}
+
+	stdDB := sql.OpenDB(connector)
+	t.Cleanup(func() { _ = stdDB.Close() })
+
+	db := esperanto.StdDB{
+		Placeholder:      "?",
+		DB:               stdDB,
+		DefaultTxOptions: &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Rollback(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// explicit options override the default
+	tx, err = db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Rollback(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []driver.TxOptions{
+		{Isolation: driver.IsolationLevel(sql.LevelReadCommitted)},
+		{Isolation: driver.IsolationLevel(sql.LevelSerializable), ReadOnly: true},
+	}
+
+	if !reflect.DeepEqual(connector.options, want) {
+		t.Errorf("got %+v, want %+v", connector.options, want)
+	}
+}
+
+func TestFinalizeEscapedPlaceholder(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t,
+		"CREATE TABLE t (doc TEXT)",
+		"INSERT INTO t (doc) VALUES ('{}')")
+
+	// the JSON path is a literal that contains ?
+	err := db.Exec(context.Background(), superbasic.Compile("UPDATE t SET doc = ?",
+		esperanto.JSONSet(esperanto.Sqlite, "doc", "why?", superbasic.Value(1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := queryInts(t, db, `SELECT COUNT(*) FROM t WHERE doc = '{"why' || CHAR(63) || '":1}'`)
+	if !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
+
+func TestQueryByKeys(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER, name TEXT)",
+		"INSERT INTO t (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c')")
+
+	type row struct {
+		id   int64
+		name string
+	}
+
+	// the columns match all columns of the table
+	models, err := esperanto.QueryByKeys(context.Background(), db, esperanto.Sqlite, "t", "id", []int64{1, 3, 4},
+		[]scan.Column[row]{
+			scan.Any(func(r *row, id int64) { r.id = id }),
+			scan.Any(func(r *row, name string) { r.name = name }),
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[int64]row{1: {id: 1, name: "a"}, 3: {id: 3, name: "c"}}; !reflect.DeepEqual(models, want) {
+		t.Errorf("got %v, want %v", models, want)
+	}
+}
+
+// closeDB records whether the context of a query is canceled when its rows are closed.
+type closeDB struct {
+	esperanto.DB
+	canceled *bool
+}
+
+func (db closeDB) Query(ctx context.Context, expression superbasic.Expression) (scan.Rows, error) {
+	rows, err := db.DB.Query(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return closeRows{Rows: rows, ctx: ctx, canceled: db.canceled}, nil
+}
+
+type closeRows struct {
+	scan.Rows
+	ctx      context.Context
+	canceled *bool
+}
+
+func (r closeRows) Close() error {
+	*r.canceled = r.ctx.Err() != nil
+
+	return r.Rows.(interface{ Close() error }).Close()
+}
+
+func TestWithCancelOnError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t,
+		"CREATE TABLE t (id)",
+		"INSERT INTO t (id) VALUES (1), (2.5), (3)")
+
+	var canceled bool
+
+	// 2.5 can't be scanned into an int64, so the third row is never read
+	_, err := esperanto.Query[int64, struct{}](ctx, closeDB{DB: db, canceled: &canceled}, esperanto.Sqlite,
+		selectIDs, struct{}{}, esperanto.WithCancelOnError())
+	if err == nil || !canceled {
+		t.Errorf("got %v and canceled %t, want a scan error and canceled", err, canceled)
+	}
+
+	if err = db.Exec(ctx, superbasic.SQL("DELETE FROM t WHERE id = 2.5")); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := esperanto.Query[int64, struct{}](ctx, closeDB{DB: db, canceled: &canceled}, esperanto.Sqlite,
+		selectIDs, struct{}{}, esperanto.WithCancelOnError())
+	if err != nil || canceled || !reflect.DeepEqual(ids, []int64{1, 3}) {
+		t.Errorf("got %v %v and canceled %t, want [1 3]", ids, err, canceled)
+	}
+}
+
+func TestGetOrCreate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+
+	columns := []scan.Column[string]{scan.Any(func(name *string, value string) { *name = value })}
+
+	for _, want := range []struct {
+		name    string
+		created bool
+	}{{name: "a", created: true}, {name: "a", created: false}} {
+		name, created, err := esperanto.GetOrCreate(ctx, db, esperanto.Sqlite,
+			superbasic.SQL("INSERT INTO t (id, name) VALUES (1, ?) ON CONFLICT DO NOTHING RETURNING name", "a"),
+			superbasic.SQL("SELECT name FROM t WHERE id = 1"), columns)
+		if err != nil || name != want.name || created != want.created {
+			t.Errorf("got %s %t %v, want %s %t", name, created, err, want.name, want.created)
+		}
+	}
+}
+
+func TestQueryMore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER)",
+		"INSERT INTO t (id) VALUES (1), (2), (3)")
+
+	ids, more, err := esperanto.QueryMore[int64, struct{}](ctx, db, esperanto.Sqlite, selectIDs, struct{}{}, 2)
+	if err != nil || !more || !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("got %v %t %v, want [1 2] with more", ids, more, err)
+	}
+
+	ids, more, err = esperanto.QueryMore[int64, struct{}](ctx, db, esperanto.Sqlite, selectIDs, struct{}{}, 3)
+	if err != nil || more || !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("got %v %t %v, want [1 2 3] without more", ids, more, err)
+	}
+}
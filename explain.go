@@ -0,0 +1,231 @@
+//nolint:wrapcheck
+package esperanto
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+// Explain wraps expr into the EXPLAIN statement of the dialect, whose output can be parsed by QueryPlan.
+// SQLServer and Oracle don't return plans from a single statement and return a MissingDialectError.
+func Explain(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("EXPLAIN (FORMAT JSON) ?", expr)
+	case MySQL:
+		return keywordCompile("EXPLAIN FORMAT=JSON ?", expr)
+	case Sqlite:
+		return keywordCompile("EXPLAIN QUERY PLAN ?", expr)
+	default:
+		return missingDialect(dialect, "Explain")
+	}
+}
+
+// Plan is the best-effort common subset of the query plans of all dialects.
+// Rows and Cost are estimates and zero if the dialect doesn't report them.
+// Raw contains the unparsed output.
+type Plan struct {
+	Rows  float64
+	Cost  float64
+	Nodes []PlanNode
+	Raw   string
+}
+
+// PlanNode is a single operation of a Plan. Depth is the nesting level in the plan tree.
+// MySQL reports no tree, so there Depth is the nesting depth of the table in the JSON output.
+// It only orders the nodes of a single plan and can't be compared with the Depth of other dialects.
+type PlanNode struct {
+	Type     string
+	Relation string
+	Rows     float64
+	Cost     float64
+	Depth    int
+}
+
+// QueryPlan runs Explain and parses its output.
+func QueryPlan(ctx context.Context, db DB, dialect Dialect, expr superbasic.Expression) (Plan, error) {
+	rows, err := db.Query(ctx, Explain(dialect, expr))
+	if err != nil {
+		return Plan{}, err
+	}
+
+	if dialect == Sqlite {
+		return sqlitePlan(rows)
+	}
+
+	lines, err := scan.All[string](rows, scan.Any(func(line *string, value string) { *line = value }))
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{Raw: strings.Join(lines, "\n")}
+
+	var output any
+
+	if err = json.Unmarshal([]byte(plan.Raw), &output); err != nil {
+		return plan, err
+	}
+
+	if dialect == Postgres {
+		postgresPlan(&plan, output)
+	} else {
+		mysqlPlan(&plan, output, 0)
+	}
+
+	return plan, nil
+}
+
+func postgresPlan(plan *Plan, output any) {
+	statements, _ := output.([]any)
+	if len(statements) == 0 {
+		return
+	}
+
+	statement, _ := statements[0].(map[string]any)
+	root, _ := statement["Plan"].(map[string]any)
+
+	plan.Rows = number(root["Plan Rows"])
+	plan.Cost = number(root["Total Cost"])
+
+	var walk func(node map[string]any, depth int)
+
+	walk = func(node map[string]any, depth int) {
+		if node == nil {
+			return
+		}
+
+		typ, _ := node["Node Type"].(string)
+		relation, _ := node["Relation Name"].(string)
+
+		plan.Nodes = append(plan.Nodes, PlanNode{
+			Type:     typ,
+			Relation: relation,
+			Rows:     number(node["Plan Rows"]),
+			Cost:     number(node["Total Cost"]),
+			Depth:    depth,
+		})
+
+		children, _ := node["Plans"].([]any)
+		for _, child := range children {
+			child, _ := child.(map[string]any)
+			walk(child, depth+1)
+		}
+	}
+
+	walk(root, 0)
+}
+
+// mysqlPlan walks the JSON output and collects all tables with an access type as nodes.
+func mysqlPlan(plan *Plan, output any, depth int) {
+	switch value := output.(type) {
+	case []any:
+		for _, each := range value {
+			mysqlPlan(plan, each, depth)
+		}
+	case map[string]any:
+		if block, ok := value["query_block"].(map[string]any); ok && depth == 0 {
+			info, _ := block["cost_info"].(map[string]any)
+			plan.Cost = number(info["query_cost"])
+		}
+
+		if access, ok := value["access_type"].(string); ok {
+			relation, _ := value["table_name"].(string)
+			info, _ := value["cost_info"].(map[string]any)
+			rows := number(value["rows_produced_per_join"])
+
+			plan.Rows += rows
+			plan.Nodes = append(plan.Nodes, PlanNode{
+				Type:     access,
+				Relation: relation,
+				Rows:     rows,
+				Cost:     number(info["prefix_cost"]),
+				Depth:    depth,
+			})
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			mysqlPlan(plan, value[key], depth+1)
+		}
+	}
+}
+
+type sqlitePlanRow struct {
+	id, parent int64
+	detail     string
+}
+
+func sqlitePlan(rows scan.Rows) (Plan, error) {
+	all, err := scan.All[sqlitePlanRow](rows,
+		scan.Any(func(row *sqlitePlanRow, id int64) { row.id = id }),
+		scan.Any(func(row *sqlitePlanRow, parent int64) { row.parent = parent }),
+		scan.Any(func(row *sqlitePlanRow, _ int64) {}),
+		scan.Any(func(row *sqlitePlanRow, detail string) { row.detail = detail }),
+	)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	var (
+		plan   Plan
+		lines  = make([]string, len(all))
+		depths = map[int64]int{}
+	)
+
+	for i, row := range all {
+		depth := 0
+		if parent, ok := depths[row.parent]; ok {
+			depth = parent + 1
+		}
+
+		depths[row.id] = depth
+		lines[i] = strings.Repeat("  ", depth) + row.detail
+
+		fields := strings.Fields(row.detail)
+		node := PlanNode{Depth: depth}
+
+		if len(fields) > 0 {
+			node.Type = fields[0]
+		}
+
+		if len(fields) > 1 && (node.Type == "SCAN" || node.Type == "SEARCH") {
+			node.Relation = fields[1]
+
+			if node.Relation == "TABLE" && len(fields) > 2 {
+				node.Relation = fields[2]
+			}
+		}
+
+		plan.Nodes = append(plan.Nodes, node)
+	}
+
+	plan.Raw = strings.Join(lines, "\n")
+
+	return plan, nil
+}
+
+// number converts JSON numbers and numeric strings to float64.
+func number(value any) float64 {
+	switch n := value.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+
+		return f
+	default:
+		return 0
+	}
+}
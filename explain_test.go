@@ -0,0 +1,56 @@
+package esperanto_test
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+// outputDB returns output as a single row for every query, like the EXPLAIN of a server.
+type outputDB struct {
+	esperanto.DB
+	output string
+}
+
+func (db outputDB) Query(ctx context.Context, _ superbasic.Expression) (scan.Rows, error) {
+	return db.DB.Query(ctx, superbasic.SQL("SELECT ?", db.output))
+}
+
+func TestQueryPlanPostgres(t *testing.T) {
+	t.Parallel()
+
+	output, err := os.ReadFile("testdata/postgres_plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := esperanto.QueryPlan(context.Background(), outputDB{DB: openSqlite(t), output: string(output)},
+		esperanto.Postgres, superbasic.SQL("SELECT * FROM orders o JOIN users u ON o.user_id = u.id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Rows != 3 || plan.Cost != 2.15 {
+		t.Errorf("got rows %v and cost %v, want 3 and 2.15", plan.Rows, plan.Cost)
+	}
+
+	if plan.Raw != string(output) {
+		t.Errorf("got raw %s, want the output", plan.Raw)
+	}
+
+	want := []esperanto.PlanNode{
+		{Type: "Hash Join", Rows: 3, Cost: 2.15, Depth: 0},
+		{Type: "Seq Scan", Relation: "orders", Rows: 3, Cost: 1.03, Depth: 1},
+		{Type: "Hash", Rows: 3, Cost: 1.03, Depth: 1},
+		{Type: "Seq Scan", Relation: "users", Rows: 3, Cost: 1.03, Depth: 2},
+	}
+
+	if !reflect.DeepEqual(plan.Nodes, want) {
+		t.Errorf("got %+v, want %+v", plan.Nodes, want)
+	}
+}
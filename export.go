@@ -0,0 +1,111 @@
+//nolint:wrapcheck
+package esperanto
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+// CSVOptions configures ExportCSV. Comma defaults to ',' and NULL values are written as Null.
+type CSVOptions struct {
+	Comma  rune
+	Header bool
+	Null   string
+}
+
+// ExportCSV streams the rows of a query as CSV to w and returns the number of rows.
+// The rows must report their columns like *sql.Rows. Timestamps are written in RFC 3339 format.
+// It takes no dialect, since the rows are read the same way for all dialects and expr is already
+// built for one. COPY (query) TO STDOUT of Postgres needs the copy API of a driver, which DB doesn't expose.
+func ExportCSV(
+	ctx context.Context,
+	db DB,
+	expr superbasic.Expression,
+	w io.Writer,
+	opts CSVOptions,
+) (int64, error) {
+	rows, err := db.Query(ctx, expr)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := writeCSV(rows, w, opts)
+	if closeErr := closeRows(rows); err == nil {
+		err = closeErr
+	}
+
+	return n, err
+}
+
+func writeCSV(rows scan.Rows, w io.Writer, opts CSVOptions) (int64, error) {
+	columnRows, ok := rows.(interface{ Columns() ([]string, error) })
+	if !ok {
+		return 0, ErrNoColumns
+	}
+
+	columns, err := columnRows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+
+	if opts.Header {
+		if err = writer.Write(columns); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		count  int64
+		values = make([]any, len(columns))
+		dest   = make([]any, len(columns))
+		record = make([]string, len(columns))
+	)
+
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return count, err
+		}
+
+		for i, value := range values {
+			switch v := value.(type) {
+			case nil:
+				record[i] = opts.Null
+			case []byte:
+				record[i] = string(v)
+			case time.Time:
+				record[i] = v.Format(time.RFC3339Nano)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+
+		if err = writer.Write(record); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		return count, err
+	}
+
+	writer.Flush()
+
+	return count, writer.Error()
+}
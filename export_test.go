@@ -0,0 +1,30 @@
+package esperanto_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+func TestExportCSV(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER, name TEXT)",
+		"INSERT INTO t (id, name) VALUES (1, 'a'), (2, NULL)")
+
+	buffer := &bytes.Buffer{}
+
+	count, err := esperanto.ExportCSV(context.Background(), db, superbasic.SQL("SELECT id, name FROM t ORDER BY id"),
+		buffer, esperanto.CSVOptions{Comma: ';', Header: true, Null: "NULL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "id;name\n1;a\n2;NULL\n"; count != 2 || buffer.String() != want {
+		t.Errorf("got %d %q, want 2 %q", count, buffer.String(), want)
+	}
+}
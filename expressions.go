@@ -0,0 +1,1149 @@
+//nolint:ireturn
+package esperanto
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wroge/superbasic"
+)
+
+// literal renders s as a quoted string literal. Placeholders are escaped.
+func literal(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "'", "''"), "?", "??") + "'"
+}
+
+// Bytes binds b as a parameter.
+// Use BytesLiteral where parameters are not allowed, e.g. in DEFAULT clauses.
+func Bytes(b []byte) superbasic.Expression {
+	return superbasic.Value(b)
+}
+
+// BytesLiteral renders b as a binary literal of the dialect.
+func BytesLiteral(dialect Dialect, b []byte) superbasic.Expression {
+	encoded := hex.EncodeToString(b)
+
+	switch dialect {
+	case Postgres:
+		return keywordSQL(fmt.Sprintf("'\\x%s'::bytea", encoded))
+	case MySQL, Sqlite:
+		return keywordSQL(fmt.Sprintf("X'%s'", encoded))
+	case SQLServer:
+		return keywordSQL("0x" + encoded)
+	case Oracle:
+		return keywordSQL(fmt.Sprintf("HEXTORAW('%s')", strings.ToUpper(encoded)))
+	default:
+		return missingDialect(dialect, "BytesLiteral")
+	}
+}
+
+// ExistsExpr wraps a subquery into EXISTS (SELECT 1 ...).
+func ExistsExpr(subquery superbasic.Expression) Exists {
+	return Exists{Subquery: subquery}
+}
+
+// NotExistsExpr wraps a subquery into NOT EXISTS (SELECT 1 ...).
+func NotExistsExpr(subquery superbasic.Expression) Exists {
+	return Exists{Subquery: subquery, Not: true}
+}
+
+// Exists is an EXISTS predicate. The select list of the subquery is replaced by SELECT 1,
+// unless KeepSelectList is set. Only plain selects are rewritten, subqueries with aggregates,
+// GROUP BY, HAVING, ORDER BY, TOP or set operations are left untouched, since their result
+// depends on the select list.
+type Exists struct {
+	Subquery       superbasic.Expression
+	Not            bool
+	KeepSelectList bool
+}
+
+func (e Exists) ToSQL() (string, []any, error) {
+	if e.Subquery == nil {
+		return "", nil, superbasic.ExpressionError{}
+	}
+
+	sql, args, err := e.Subquery.ToSQL()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !e.KeepSelectList {
+		sql, args = selectOne(sql, args)
+	}
+
+	if e.Not {
+		return caseKeywords("NOT EXISTS (") + sql + ")", args, nil
+	}
+
+	return caseKeywords("EXISTS (") + sql + ")", args, nil
+}
+
+// aggregates are functions that make a select list decide about the rows of a query.
+var aggregates = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true, "ARRAY_AGG": true, "STRING_AGG": true,
+	"JSON_AGG": true, "JSONB_AGG": true, "GROUP_CONCAT": true, "LISTAGG": true, "JSON_GROUP_ARRAY": true,
+	"JSON_ARRAYAGG": true, "JSON_OBJECTAGG": true, "BOOL_AND": true, "BOOL_OR": true, "EVERY": true,
+	"STDDEV": true, "VARIANCE": true, "TOTAL": true,
+}
+
+// unsafeClauses are top-level keywords after which a select list can't be replaced.
+var unsafeClauses = map[string]bool{
+	"GROUP": true, "HAVING": true, "ORDER": true, "UNION": true, "EXCEPT": true, "INTERSECT": true,
+	"MINUS": true, "WINDOW": true,
+}
+
+// selectOne replaces the top-level select list by 1 and drops the arguments of its placeholders.
+// The query is returned unchanged if it isn't a plain SELECT ... FROM.
+//
+//nolint:cyclop
+func selectOne(sql string, args []any) (string, []any) {
+	tokens := lex(sql)
+
+	start := 0
+	for start < len(tokens) && (tokens[start].kind == tokenSpace || tokens[start].kind == tokenComment) {
+		start++
+	}
+
+	if start == len(tokens) || !isKeyword(tokens[start], "SELECT") {
+		return sql, args
+	}
+
+	from := -1
+	depth := 0
+
+	for i := start + 1; i < len(tokens); i++ {
+		switch t := tokens[i]; {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case t.kind != tokenWord:
+		case from < 0 && (aggregates[strings.ToUpper(t.text)] || isKeyword(t, "TOP")):
+			return sql, args
+		case depth == 0 && from < 0 && isKeyword(t, "FROM"):
+			from = i
+		case depth == 0 && from >= 0 && unsafeClauses[strings.ToUpper(t.text)]:
+			return sql, args
+		}
+	}
+
+	if from < 0 {
+		return sql, args
+	}
+
+	// superbasic counts placeholders regardless of quotes
+	_, placeholders := superbasic.Replace("?", join(tokens[start:from]))
+	if placeholders > len(args) {
+		return sql, args
+	}
+
+	return join(tokens[:start]) + caseKeywords("SELECT 1 ") + join(tokens[from:]), args[placeholders:]
+}
+
+// DateUnit is a portable unit of time.
+type DateUnit string
+
+const (
+	UnitSecond DateUnit = "second"
+	UnitMinute DateUnit = "minute"
+	UnitHour   DateUnit = "hour"
+	UnitDay    DateUnit = "day"
+	UnitWeek   DateUnit = "week"
+	UnitMonth  DateUnit = "month"
+	UnitYear   DateUnit = "year"
+)
+
+func (u DateUnit) valid() bool {
+	switch u {
+	case UnitSecond, UnitMinute, UnitHour, UnitDay, UnitWeek, UnitMonth, UnitYear:
+		return true
+	default:
+		return false
+	}
+}
+
+func unknownUnit(unit DateUnit) superbasic.Expression {
+	return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: unknown date unit '%s'", unit)}
+}
+
+var (
+	mysqlTruncFormats = map[DateUnit]string{
+		UnitSecond: "%Y-%m-%d %H:%i:%s",
+		UnitMinute: "%Y-%m-%d %H:%i:00",
+		UnitHour:   "%Y-%m-%d %H:00:00",
+		UnitDay:    "%Y-%m-%d 00:00:00",
+		UnitMonth:  "%Y-%m-01 00:00:00",
+		UnitYear:   "%Y-01-01 00:00:00",
+	}
+	sqliteTruncFormats = map[DateUnit]string{
+		UnitSecond: "%Y-%m-%d %H:%M:%S",
+		UnitMinute: "%Y-%m-%d %H:%M:00",
+		UnitHour:   "%Y-%m-%d %H:00:00",
+		UnitDay:    "%Y-%m-%d 00:00:00",
+		UnitMonth:  "%Y-%m-01 00:00:00",
+		UnitYear:   "%Y-01-01 00:00:00",
+	}
+	oracleTruncFormats = map[DateUnit]string{
+		UnitMinute: "MI",
+		UnitHour:   "HH24",
+		UnitDay:    "DD",
+		UnitWeek:   "IW",
+		UnitMonth:  "MM",
+		UnitYear:   "YYYY",
+	}
+)
+
+// DateTrunc truncates a timestamp to the beginning of unit. Weeks start on monday.
+// SQLServer uses the DATEADD/DATEDIFF emulation, so it works before DATETRUNC was introduced in 2022.
+func DateTrunc(dialect Dialect, unit DateUnit, expr superbasic.Expression) superbasic.Expression {
+	if !unit.valid() {
+		return unknownUnit(unit)
+	}
+
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("DATE_TRUNC('%s', ?)", unit), expr)
+	case MySQL:
+		if unit == UnitWeek {
+			return keywordCompile("CAST(DATE_SUB(DATE(?), INTERVAL WEEKDAY(?) DAY) AS DATETIME)", expr, expr)
+		}
+
+		return keywordCompile(fmt.Sprintf("CAST(DATE_FORMAT(?, '%s') AS DATETIME)", mysqlTruncFormats[unit]), expr)
+	case Sqlite:
+		if unit == UnitWeek {
+			return keywordCompile("strftime('%Y-%m-%d 00:00:00', ?, 'weekday 0', '-6 days')", expr)
+		}
+
+		return keywordCompile(fmt.Sprintf("strftime('%s', ?)", sqliteTruncFormats[unit]), expr)
+	case SQLServer:
+		switch unit {
+		case UnitSecond:
+			// DATEDIFF in seconds overflows from 1900, so a later base is used.
+			return keywordCompile(
+				"DATEADD(second, DATEDIFF(second, '2000-01-01', ?), CAST('2000-01-01' AS DATETIME2))", expr)
+		case UnitWeek:
+			// 0 is monday 1900-01-01, shifting by a day moves sundays into the previous week.
+			return keywordCompile("DATEADD(week, DATEDIFF(week, 0, DATEADD(day, -1, ?)), 0)", expr)
+		default:
+			return keywordCompile(fmt.Sprintf("DATEADD(%s, DATEDIFF(%s, 0, ?), 0)", unit, unit), expr)
+		}
+	case Oracle:
+		if unit == UnitSecond {
+			return keywordCompile("CAST(? AS DATE)", expr)
+		}
+
+		return keywordCompile(fmt.Sprintf("TRUNC(?, '%s')", oracleTruncFormats[unit]), expr)
+	default:
+		return missingDialect(dialect, "DateTrunc")
+	}
+}
+
+// In renders column IN (?, ...). Without values a predicate is rendered that is always false.
+func In[T any](column string, values []T) superbasic.Expression {
+	if len(values) == 0 {
+		return keywordSQL("1 = 0")
+	}
+
+	return keywordCompile(column+" IN ?", superbasic.Values(superbasic.Map(values, func(_ int, value T) any {
+		return value
+	})))
+}
+
+// NullSafeEquals compares a and b and treats two NULLs as equal.
+func NullSafeEquals(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("? IS NOT DISTINCT FROM ?", a, b)
+	case Sqlite:
+		return keywordCompile("? IS ?", a, b)
+	case MySQL:
+		return keywordCompile("? <=> ?", a, b)
+	case SQLServer:
+		return keywordCompile("EXISTS (SELECT ? INTERSECT SELECT ?)", a, b)
+	case Oracle:
+		return keywordCompile("DECODE(?, ?, 1, 0) = 1", a, b)
+	default:
+		return missingDialect(dialect, "NullSafeEquals")
+	}
+}
+
+// Interval renders an interval literal of n units.
+// SQLServer has no interval type and returns a MissingDialectError, use DateAdd instead.
+// Sqlite renders a date modifier like '+1 days'.
+func Interval(dialect Dialect, n int, unit DateUnit) superbasic.Expression {
+	if !unit.valid() {
+		return unknownUnit(unit)
+	}
+
+	switch dialect {
+	case Postgres:
+		return keywordSQL(fmt.Sprintf("INTERVAL '%d %s'", n, unit))
+	case MySQL:
+		return keywordSQL(fmt.Sprintf("INTERVAL %d %s", n, strings.ToUpper(string(unit))))
+	case Oracle:
+		if unit == UnitWeek {
+			n, unit = n*7, UnitDay
+		}
+
+		return keywordSQL(fmt.Sprintf("INTERVAL '%d' %s(9)", n, strings.ToUpper(string(unit))))
+	case Sqlite:
+		if unit == UnitWeek {
+			n, unit = n*7, UnitDay
+		}
+
+		return keywordSQL(fmt.Sprintf("'%+d %ss'", n, unit))
+	default:
+		return missingDialect(dialect, "Interval")
+	}
+}
+
+// DateAdd adds n units to a timestamp.
+func DateAdd(dialect Dialect, expr superbasic.Expression, n int, unit DateUnit) superbasic.Expression {
+	switch dialect {
+	case Postgres, Oracle:
+		return keywordCompile("(? + ?)", expr, Interval(dialect, n, unit))
+	case MySQL:
+		return keywordCompile("DATE_ADD(?, ?)", expr, Interval(dialect, n, unit))
+	case Sqlite:
+		return keywordCompile("datetime(?, ?)", expr, Interval(dialect, n, unit))
+	case SQLServer:
+		if !unit.valid() {
+			return unknownUnit(unit)
+		}
+
+		return keywordCompile(fmt.Sprintf("DATEADD(%s, %d, ?)", unit, n), expr)
+	default:
+		return missingDialect(dialect, "DateAdd")
+	}
+}
+
+// RandomOrder renders a random value to be used in ORDER BY.
+func RandomOrder(dialect Dialect) superbasic.Expression {
+	switch dialect {
+	case Postgres, Sqlite:
+		return keywordSQL("RANDOM()")
+	case MySQL:
+		return keywordSQL("RAND()")
+	case SQLServer:
+		return keywordSQL("NEWID()")
+	case Oracle:
+		return keywordSQL("DBMS_RANDOM.VALUE")
+	default:
+		return missingDialect(dialect, "RandomOrder")
+	}
+}
+
+// SeededRandomOrder renders a repeatable random value to be used in ORDER BY.
+// Only MySQL accepts a seed in the expression, Postgres and Oracle are seeded by SetRandomSeed.
+func SeededRandomOrder(dialect Dialect, seed int64) superbasic.Expression {
+	switch dialect {
+	case MySQL:
+		return keywordSQL("RAND(?)", seed)
+	case Postgres, Oracle:
+		return RandomOrder(dialect)
+	default:
+		return missingDialect(dialect, "SeededRandomOrder")
+	}
+}
+
+// SetRandomSeed seeds the random values of the session, so it must run on the same connection,
+// e.g. in the same transaction. The seed must be between -1 and 1.
+func SetRandomSeed(dialect Dialect, seed float64) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordSQL("SELECT setseed(?)", seed)
+	case Oracle:
+		return keywordSQL("BEGIN DBMS_RANDOM.SEED(?); END;", fmt.Sprint(seed))
+	default:
+		return missingDialect(dialect, "SetRandomSeed")
+	}
+}
+
+// TrimSide is the side of a string that is trimmed.
+type TrimSide string
+
+const (
+	TrimBoth     TrimSide = "BOTH"
+	TrimLeading  TrimSide = "LEADING"
+	TrimTrailing TrimSide = "TRAILING"
+)
+
+// Trim removes chars from a side of expr. Empty chars remove spaces.
+// MySQL removes chars as a whole string instead of a set of characters.
+// SQLServer needs version 2022 to trim other characters than spaces.
+func Trim(dialect Dialect, expr superbasic.Expression, chars string, side TrimSide) superbasic.Expression {
+	if side != TrimBoth && side != TrimLeading && side != TrimTrailing {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: unknown trim side '%s'", side)}
+	}
+
+	if chars == "" {
+		chars = " "
+	}
+
+	value := superbasic.Value(chars)
+
+	switch dialect {
+	case Postgres, MySQL:
+		return keywordCompile(fmt.Sprintf("TRIM(%s ? FROM ?)", side), value, expr)
+	case SQLServer:
+		if chars != " " {
+			return keywordCompile(fmt.Sprintf("TRIM(%s ? FROM ?)", side), value, expr)
+		}
+
+		return trimSide(side,
+			keywordCompile("LTRIM(?)", expr),
+			keywordCompile("RTRIM(?)", expr),
+			keywordCompile("LTRIM(RTRIM(?))", expr))
+	case Sqlite:
+		return trimSide(side,
+			keywordCompile("LTRIM(?, ?)", expr, value),
+			keywordCompile("RTRIM(?, ?)", expr, value),
+			keywordCompile("TRIM(?, ?)", expr, value))
+	case Oracle:
+		// TRIM only accepts a single character.
+		return trimSide(side,
+			keywordCompile("LTRIM(?, ?)", expr, value),
+			keywordCompile("RTRIM(?, ?)", expr, value),
+			keywordCompile("LTRIM(RTRIM(?, ?), ?)", expr, value, value))
+	default:
+		return missingDialect(dialect, "Trim")
+	}
+}
+
+func trimSide(side TrimSide, leading, trailing, both superbasic.Expression) superbasic.Expression {
+	switch side {
+	case TrimLeading:
+		return leading
+	case TrimTrailing:
+		return trailing
+	default:
+		return both
+	}
+}
+
+// FTOptions configures FullTextMatch.
+// Language is used by Postgres and SQLServer, other dialects take the language from the index.
+// FTS5Table enables full-text search on Sqlite by matching against a FTS5 virtual table.
+type FTOptions struct {
+	Language  string
+	FTS5Table string
+}
+
+// FullTextMatch renders a predicate that matches the terms of query against a full-text index on columns.
+func FullTextMatch(dialect Dialect, columns []string, query string, opts FTOptions) superbasic.Expression {
+	if len(columns) == 0 && !(dialect == Sqlite && opts.FTS5Table != "") {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: full-text match needs at least one column")}
+	}
+
+	switch dialect {
+	case Postgres:
+		document := strings.Join(superbasic.Map(columns, func(_ int, column string) string {
+			return fmt.Sprintf("COALESCE(%s, '')", column)
+		}), " || ' ' || ")
+
+		if opts.Language == "" {
+			return keywordSQL(fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(?)", document), query)
+		}
+
+		return keywordSQL(fmt.Sprintf("to_tsvector(%s, %s) @@ plainto_tsquery(%s, ?)",
+			literal(opts.Language), document, literal(opts.Language)), query)
+	case MySQL:
+		return keywordSQL(fmt.Sprintf("MATCH (%s) AGAINST (? IN NATURAL LANGUAGE MODE)", strings.Join(columns, ", ")), query)
+	case SQLServer:
+		if opts.Language == "" {
+			return keywordSQL(fmt.Sprintf("FREETEXT((%s), ?)", strings.Join(columns, ", ")), query)
+		}
+
+		return keywordSQL(fmt.Sprintf("FREETEXT((%s), ?, LANGUAGE %s)", strings.Join(columns, ", "), literal(opts.Language)), query)
+	case Oracle:
+		// CONTAINS only accepts a single column.
+		return keywordCompile("(?)", keywordJoin(" OR ", superbasic.Map(columns, func(_ int, column string) superbasic.Expression {
+			return keywordSQL(fmt.Sprintf("CONTAINS(%s, ?) > 0", column), query)
+		})...))
+	case Sqlite:
+		if opts.FTS5Table == "" {
+			return missingDialect(dialect, "FullTextMatch without FTS5Table")
+		}
+
+		return keywordSQL(opts.FTS5Table+" MATCH ?", query)
+	default:
+		return missingDialect(dialect, "FullTextMatch")
+	}
+}
+
+// Bool renders b as a boolean literal. SQLServer and Oracle have no boolean literals and render 1 or 0.
+func Bool(dialect Dialect, b bool) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		if b {
+			return keywordSQL("TRUE")
+		}
+
+		return keywordSQL("FALSE")
+	case SQLServer, Oracle:
+		if b {
+			return keywordSQL("1")
+		}
+
+		return keywordSQL("0")
+	default:
+		return missingDialect(dialect, "Bool")
+	}
+}
+
+// quoteIdent quotes name as identifier of the dialect. Oracle uppercases name to match unquoted identifiers.
+func quoteIdent(dialect Dialect, name string) (string, bool) {
+	name = strings.ReplaceAll(name, "?", "??")
+
+	switch dialect {
+	case Postgres, Sqlite:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`, true
+	case Oracle:
+		return `"` + strings.ReplaceAll(strings.ToUpper(name), `"`, `""`) + `"`, true
+	case MySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`", true
+	case SQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]", true
+	default:
+		return "", false
+	}
+}
+
+// Ident renders name as quoted identifier.
+func Ident(dialect Dialect, name string) superbasic.Expression {
+	quoted, ok := quoteIdent(dialect, name)
+	if !ok {
+		return missingDialect(dialect, "Ident")
+	}
+
+	return keywordSQL(quoted)
+}
+
+// Table renders a quoted table name qualified by schema. An empty schema is omitted.
+// In MySQL the schema is the database.
+func Table(dialect Dialect, schema, name string) superbasic.Expression {
+	if schema == "" {
+		return Ident(dialect, name)
+	}
+
+	return keywordCompile("?.?", Ident(dialect, schema), Ident(dialect, name))
+}
+
+// Round rounds expr to places decimal places. Postgres casts expr to NUMERIC.
+func Round(dialect Dialect, expr superbasic.Expression, places int) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("ROUND(CAST(? AS NUMERIC), %d)", places), expr)
+	case MySQL, Sqlite, SQLServer, Oracle:
+		return keywordCompile(fmt.Sprintf("ROUND(?, %d)", places), expr)
+	default:
+		return missingDialect(dialect, "Round")
+	}
+}
+
+// Truncate cuts expr off after places decimal places. Postgres casts expr to NUMERIC
+// and Sqlite casts the shifted value to INTEGER.
+func Truncate(dialect Dialect, expr superbasic.Expression, places int) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("TRUNC(CAST(? AS NUMERIC), %d)", places), expr)
+	case MySQL:
+		return keywordCompile(fmt.Sprintf("TRUNCATE(?, %d)", places), expr)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("ROUND(?, %d, 1)", places), expr)
+	case Oracle:
+		return keywordCompile(fmt.Sprintf("TRUNC(?, %d)", places), expr)
+	case Sqlite:
+		factor := strconv.FormatFloat(math.Pow10(places), 'f', -1, 64)
+		if !strings.Contains(factor, ".") {
+			factor += ".0"
+		}
+
+		return keywordCompile(fmt.Sprintf("(CAST(? * %s AS INTEGER) / %s)", factor, factor), expr)
+	default:
+		return missingDialect(dialect, "Truncate")
+	}
+}
+
+// ToJSON casts a text expr to the JSON type of the dialect. SQLServer has no JSON type
+// and validates the text with JSON_QUERY, Oracle needs version 18.
+func ToJSON(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("CAST(? AS JSONB)", expr)
+	case MySQL:
+		return keywordCompile("CAST(? AS JSON)", expr)
+	case Sqlite:
+		return keywordCompile("json(?)", expr)
+	case SQLServer:
+		return keywordCompile("JSON_QUERY(?)", expr)
+	case Oracle:
+		return keywordCompile("TREAT(? AS JSON)", expr)
+	default:
+		return missingDialect(dialect, "ToJSON")
+	}
+}
+
+// IsValidJSON renders a predicate that is true if expr is valid JSON text. Postgres needs version 16.
+func IsValidJSON(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, Oracle:
+		return keywordCompile("? IS JSON", expr)
+	case MySQL:
+		return keywordCompile("JSON_VALID(?) = 1", expr)
+	case Sqlite:
+		return keywordCompile("json_valid(?) = 1", expr)
+	case SQLServer:
+		return keywordCompile("ISJSON(?) = 1", expr)
+	default:
+		return missingDialect(dialect, "IsValidJSON")
+	}
+}
+
+// Window is the window of a window function. Frame is an optional frame clause like
+// ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW.
+// IgnoreNulls skips NULL values in functions like LAST_VALUE and is only supported by
+// Oracle and SQLServer 2022, other dialects return a MissingDialectError.
+type Window struct {
+	PartitionBy []string
+	OrderBy     []OrderItem
+	Frame       string
+	IgnoreNulls bool
+}
+
+// Over applies function to a window, e.g. Over(dialect, keywordSQL("LAST_VALUE(x)"), window).
+func Over(dialect Dialect, function superbasic.Expression, window Window) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer, Oracle:
+	default:
+		return missingDialect(dialect, "Over")
+	}
+
+	var nulls superbasic.Expression = superbasic.Raw{}
+
+	if window.IgnoreNulls {
+		if dialect != Oracle && dialect != SQLServer {
+			return missingDialect(dialect, "IGNORE NULLS")
+		}
+
+		nulls = keywordSQL("IGNORE NULLS")
+	}
+
+	var partition superbasic.Expression = superbasic.Raw{}
+	if len(window.PartitionBy) > 0 {
+		partition = keywordSQL("PARTITION BY " + strings.Join(window.PartitionBy, ", "))
+	}
+
+	return superbasic.Join(" ", function, nulls, keywordCompile("OVER (?)",
+		superbasic.Join(" ", partition, OrderBy(dialect, window.OrderBy...), keywordSQL(window.Frame))))
+}
+
+// CurrentUser renders the user of the session, e.g. for audit columns.
+// Sqlite has no users and returns a MissingDialectError.
+func CurrentUser(dialect Dialect) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordSQL("CURRENT_USER")
+	case MySQL:
+		return keywordSQL("CURRENT_USER()")
+	case SQLServer:
+		return keywordSQL("SUSER_SNAME()")
+	case Oracle:
+		return keywordSQL("USER")
+	default:
+		return missingDialect(dialect, "CurrentUser")
+	}
+}
+
+// Not negates a predicate. On SQLServer and Oracle a bare column is compared to 0 instead,
+// since they have no boolean type and store flags as BIT or NUMBER(1).
+func Not(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	if expr == nil {
+		return superbasic.Raw{Err: superbasic.ExpressionError{}}
+	}
+
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		return keywordCompile("NOT (?)", expr)
+	case SQLServer, Oracle:
+		return deferred(func() superbasic.Expression {
+			sql, args, err := expr.ToSQL()
+			if err != nil {
+				return superbasic.Raw{Err: err}
+			}
+
+			if len(args) == 0 && isColumnRef(sql) {
+				return keywordCompile("(? = 0)", expr)
+			}
+
+			return keywordCompile("NOT (?)", expr)
+		})
+	default:
+		return missingDialect(dialect, "Not")
+	}
+}
+
+// isColumnRef reports whether sql is a possibly qualified column name like t.flag, "t"."flag" or [t].[flag].
+func isColumnRef(sql string) bool {
+	tokens := lex(strings.TrimSpace(sql))
+
+	if len(tokens) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(tokens); {
+		switch t := tokens[i]; {
+		case t.kind == tokenIdentifier:
+			i++
+		case t.kind == tokenWord:
+			if ('0' <= t.text[0] && t.text[0] <= '9') || isKeyword(t, "TRUE") || isKeyword(t, "FALSE") ||
+				isKeyword(t, "NULL") {
+				return false
+			}
+
+			i++
+		default:
+			return false
+		}
+
+		if i == len(tokens) {
+			return true
+		}
+
+		if tokens[i].text != "." {
+			return false
+		}
+
+		i++
+	}
+
+	return false
+}
+
+// ToEpoch converts a timestamp to seconds since 1970-01-01. Time zones are ignored in SQLServer and Oracle.
+func ToEpoch(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("EXTRACT(EPOCH FROM ?)", expr)
+	case MySQL:
+		return keywordCompile("UNIX_TIMESTAMP(?)", expr)
+	case Sqlite:
+		return keywordCompile("CAST(strftime('%s', ?) AS INTEGER)", expr)
+	case SQLServer:
+		return keywordCompile("DATEDIFF_BIG(second, '1970-01-01', ?)", expr)
+	case Oracle:
+		return keywordCompile("ROUND((CAST(? AS DATE) - DATE '1970-01-01') * 86400)", expr)
+	default:
+		return missingDialect(dialect, "ToEpoch")
+	}
+}
+
+// FromEpoch converts seconds since 1970-01-01 to a timestamp.
+func FromEpoch(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("to_timestamp(?)", expr)
+	case MySQL:
+		return keywordCompile("FROM_UNIXTIME(?)", expr)
+	case Sqlite:
+		return keywordCompile("datetime(?, 'unixepoch')", expr)
+	case SQLServer:
+		return keywordCompile("DATEADD(second, ?, '1970-01-01')", expr)
+	case Oracle:
+		return keywordCompile("(TIMESTAMP '1970-01-01 00:00:00' + NUMTODSINTERVAL(?, 'SECOND'))", expr)
+	default:
+		return missingDialect(dialect, "FromEpoch")
+	}
+}
+
+// IsNull renders column IS NULL. The column is quoted, a qualified column like t.c is quoted per part.
+func IsNull(dialect Dialect, column string) superbasic.Expression {
+	return keywordCompile("? IS NULL", qualifiedIdent(dialect, column))
+}
+
+// IsNotNull renders column IS NOT NULL. The column is quoted, a qualified column like t.c is quoted per part.
+func IsNotNull(dialect Dialect, column string) superbasic.Expression {
+	return keywordCompile("? IS NOT NULL", qualifiedIdent(dialect, column))
+}
+
+func qualifiedIdent(dialect Dialect, name string) superbasic.Expression {
+	return superbasic.Join(".", superbasic.Map(strings.Split(name, "."), func(_ int, part string) superbasic.Expression {
+		return Ident(dialect, part)
+	})...)
+}
+
+// JSONSet sets the value at path in the JSON of column, e.g. in a SET clause. The path is a list of keys
+// separated by dots like address.city, the forms $.address.city and {address,city} are accepted as well.
+// Postgres needs a JSONB value, e.g. by ToJSON, and Oracle needs version 21.
+func JSONSet(dialect Dialect, column, path string, value superbasic.Expression) superbasic.Expression {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	keys := strings.FieldsFunc(path, func(r rune) bool { return r == '.' || r == ',' })
+
+	if len(keys) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: JSONSet of '%s' with empty path", column)}
+	}
+
+	jsonPath := literal("$." + strings.Join(keys, "."))
+
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("jsonb_set(%s, %s, ?)", column, literal("{"+strings.Join(keys, ",")+"}")), value)
+	case MySQL, Sqlite:
+		return keywordCompile(fmt.Sprintf("JSON_SET(%s, %s, ?)", column, jsonPath), value)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("JSON_MODIFY(%s, %s, ?)", column, jsonPath), value)
+	case Oracle:
+		return keywordCompile(fmt.Sprintf("JSON_TRANSFORM(%s, SET %s = ?)", column, jsonPath), value)
+	default:
+		return missingDialect(dialect, "JSONSet")
+	}
+}
+
+// AtTimeZone converts a timestamp to the time zone tz. MySQL converts from the session time zone
+// and SQLServer expects Windows time zone names like 'W. Europe Standard Time'.
+func AtTimeZone(dialect Dialect, expr superbasic.Expression, tz string) superbasic.Expression {
+	switch dialect {
+	case Postgres, SQLServer, Oracle:
+		return keywordCompile(fmt.Sprintf("(? AT TIME ZONE %s)", literal(tz)), expr)
+	case MySQL:
+		return keywordCompile(fmt.Sprintf("CONVERT_TZ(?, @@session.time_zone, %s)", literal(tz)), expr)
+	default:
+		return missingDialect(dialect, "AtTimeZone")
+	}
+}
+
+// Fragments are named expressions that are defined once and inlined wherever they are referenced.
+type Fragments map[string]superbasic.Expression
+
+// Fragment defines the fragment name and returns expr.
+func (f Fragments) Fragment(name string, expr superbasic.Expression) superbasic.Expression {
+	f[name] = expr
+
+	return expr
+}
+
+// Ref references the fragment name. It is resolved when the expression is rendered,
+// so the fragment can be defined after it is referenced.
+func (f Fragments) Ref(name string) superbasic.Expression {
+	return fragmentRef{fragments: f, name: name}
+}
+
+type fragmentRef struct {
+	fragments Fragments
+	name      string
+}
+
+func (r fragmentRef) ToSQL() (string, []any, error) {
+	expr, ok := r.fragments[r.name]
+	if !ok || expr == nil {
+		return "", nil, fmt.Errorf("wroge/esperanto error: unknown fragment '%s'", r.name)
+	}
+
+	return expr.ToSQL()
+}
+
+// maxPrecisions are the maximum precisions of decimals by dialect. Postgres and Sqlite have no practical limit.
+var maxPrecisions = map[Dialect]int{MySQL: 65, SQLServer: 38, Oracle: 38}
+
+// Decimal renders the numeric string s as an exact decimal literal, so it isn't interpreted as a float.
+// Precision and scale of the cast are taken from s. Sqlite has no exact decimal type.
+func Decimal(dialect Dialect, s string) superbasic.Expression {
+	precision, scale, ok := decimalDigits(s)
+	if !ok {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: '%s' is not a decimal", s)}
+	}
+
+	if limit, ok := maxPrecisions[dialect]; (ok && precision > limit) || (dialect == MySQL && scale > 30) {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: decimal '%s' exceeds the precision of %s", s, dialect)}
+	}
+
+	switch dialect {
+	case Postgres:
+		return keywordSQL(fmt.Sprintf("CAST('%s' AS NUMERIC)", s))
+	case MySQL, Sqlite, SQLServer:
+		return keywordSQL(fmt.Sprintf("CAST(%s AS DECIMAL(%d, %d))", s, precision, scale))
+	case Oracle:
+		return keywordSQL(fmt.Sprintf("CAST(%s AS NUMBER(%d, %d))", s, precision, scale))
+	default:
+		return missingDialect(dialect, "Decimal")
+	}
+}
+
+// decimalDigits returns the precision and scale of a decimal like -12.340.
+func decimalDigits(s string) (int, int, bool) {
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(s, ".")
+
+	if integer+fraction == "" {
+		return 0, 0, false
+	}
+
+	for _, r := range integer + fraction {
+		if r < '0' || r > '9' {
+			return 0, 0, false
+		}
+	}
+
+	integer = strings.TrimLeft(integer, "0")
+
+	if integer+fraction == "" {
+		return 1, 0, true
+	}
+
+	return len(integer) + len(fraction), len(fraction), true
+}
+
+// BitAnd renders the bitwise AND of a and b.
+func BitAnd(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer:
+		return keywordCompile("(? & ?)", a, b)
+	case Oracle:
+		return keywordCompile("BITAND(?, ?)", a, b)
+	default:
+		return missingDialect(dialect, "BitAnd")
+	}
+}
+
+// BitOr renders the bitwise OR of a and b. Oracle emulates it with BITAND.
+func BitOr(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer:
+		return keywordCompile("(? | ?)", a, b)
+	case Oracle:
+		return keywordCompile("(? + ? - BITAND(?, ?))", a, b, a, b)
+	default:
+		return missingDialect(dialect, "BitOr")
+	}
+}
+
+// BitXor renders the bitwise XOR of a and b. Postgres uses #, Sqlite and Oracle emulate it.
+func BitXor(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("(? # ?)", a, b)
+	case MySQL, SQLServer:
+		return keywordCompile("(? ^ ?)", a, b)
+	case Sqlite:
+		return keywordCompile("((? | ?) - (? & ?))", a, b, a, b)
+	case Oracle:
+		return keywordCompile("(? + ? - 2 * BITAND(?, ?))", a, b, a, b)
+	default:
+		return missingDialect(dialect, "BitXor")
+	}
+}
+
+// Mod renders the remainder of a divided by b.
+func Mod(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, SQLServer:
+		return keywordCompile("(? % ?)", a, b)
+	case Oracle:
+		return keywordCompile("MOD(?, ?)", a, b)
+	default:
+		return missingDialect(dialect, "Mod")
+	}
+}
+
+// IntDiv divides a by b and truncates the result towards zero.
+func IntDiv(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("DIV(CAST(? AS NUMERIC), CAST(? AS NUMERIC))", a, b)
+	case MySQL:
+		return keywordCompile("(? DIV ?)", a, b)
+	case Sqlite:
+		return keywordCompile("CAST(? / ? AS INTEGER)", a, b)
+	case SQLServer:
+		return keywordCompile("CAST(? / ? AS BIGINT)", a, b)
+	case Oracle:
+		return keywordCompile("TRUNC(? / ?)", a, b)
+	default:
+		return missingDialect(dialect, "IntDiv")
+	}
+}
+
+// Position returns the 1-based position of substr in str, 0 if it isn't found.
+func Position(dialect Dialect, substr, str superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("STRPOS(?, ?)", str, substr)
+	case MySQL:
+		return keywordCompile("LOCATE(?, ?)", substr, str)
+	case SQLServer:
+		return keywordCompile("CHARINDEX(?, ?)", substr, str)
+	case Sqlite, Oracle:
+		return keywordCompile("INSTR(?, ?)", str, substr)
+	default:
+		return missingDialect(dialect, "Position")
+	}
+}
+
+// LPad pads the string expr on the left with pad to length characters. Longer strings are cut to length.
+// SQLServer and Sqlite emulate it.
+func LPad(dialect Dialect, expr superbasic.Expression, length int, pad string) superbasic.Expression {
+	if pad == "" || length < 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: invalid LPad to length %d with pad '%s'", length, pad)}
+	}
+
+	value := superbasic.Value(pad)
+
+	switch dialect {
+	case Postgres, MySQL, Oracle:
+		return keywordCompile(fmt.Sprintf("LPAD(?, %d, ?)", length), expr, value)
+	case SQLServer:
+		// LEN ignores trailing spaces
+		return keywordCompile(fmt.Sprintf("LEFT(REPLICATE(?, %d), %d - (LEN(LEFT(?, %d) + 'x') - 1)) + LEFT(?, %d)",
+			length, length, length, length), value, expr, expr)
+	case Sqlite:
+		return keywordCompile(fmt.Sprintf("SUBSTR(REPLACE(HEX(ZEROBLOB(%d)), '00', ?), 1, MAX(%d - LENGTH(?), 0)) || "+
+			"SUBSTR(?, 1, %d)", length, length, length), value, expr, expr)
+	default:
+		return missingDialect(dialect, "LPad")
+	}
+}
+
+// RPad pads the string expr on the right with pad to length characters. Longer strings are cut to length.
+// SQLServer and Sqlite emulate it.
+func RPad(dialect Dialect, expr superbasic.Expression, length int, pad string) superbasic.Expression {
+	if pad == "" || length < 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: invalid RPad to length %d with pad '%s'", length, pad)}
+	}
+
+	value := superbasic.Value(pad)
+
+	switch dialect {
+	case Postgres, MySQL, Oracle:
+		return keywordCompile(fmt.Sprintf("RPAD(?, %d, ?)", length), expr, value)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("LEFT(LEFT(?, %d) + REPLICATE(?, %d), %d)", length, length, length),
+			expr, value)
+	case Sqlite:
+		return keywordCompile(fmt.Sprintf("SUBSTR(SUBSTR(?, 1, %d) || REPLACE(HEX(ZEROBLOB(%d)), '00', ?), 1, %d)",
+			length, length, length), expr, value)
+	default:
+		return missingDialect(dialect, "RPad")
+	}
+}
+
+// SumOrZero sums expr and returns 0 instead of NULL for no rows.
+func SumOrZero(expr superbasic.Expression) superbasic.Expression {
+	return keywordCompile("COALESCE(SUM(?), 0)", expr)
+}
+
+// AvgOrNull averages expr and returns NULL for no rows.
+// SQLServer casts expr to FLOAT, so that the average of integers isn't truncated.
+func AvgOrNull(dialect Dialect, expr superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite, Oracle:
+		return keywordCompile("AVG(?)", expr)
+	case SQLServer:
+		return keywordCompile("AVG(CAST(? AS FLOAT))", expr)
+	default:
+		return missingDialect(dialect, "AvgOrNull")
+	}
+}
+
+// CountDistinct counts the distinct combinations of exprs. Postgres compares a row value and MySQL
+// counts multiple expressions natively. Sqlite, SQLServer and Oracle concatenate the text of exprs
+// separated by |, so values containing | can collide and the NULL handling differs, Oracle treats NULL as empty.
+func CountDistinct(dialect Dialect, exprs ...superbasic.Expression) superbasic.Expression {
+	if len(exprs) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: CountDistinct without expressions")}
+	}
+
+	if len(exprs) == 1 {
+		return keywordCompile("COUNT(DISTINCT ?)", exprs[0])
+	}
+
+	switch dialect {
+	case Postgres:
+		return keywordCompile("COUNT(DISTINCT (?))", superbasic.Join(", ", exprs...))
+	case MySQL:
+		return keywordCompile("COUNT(DISTINCT ?)", superbasic.Join(", ", exprs...))
+	case Sqlite, Oracle:
+		return keywordCompile("COUNT(DISTINCT ?)", superbasic.Join(" || '|' || ", exprs...))
+	case SQLServer:
+		return keywordCompile("COUNT(DISTINCT ?)", superbasic.Join(" + '|' + ",
+			superbasic.Map(exprs, func(_ int, expr superbasic.Expression) superbasic.Expression {
+				return keywordCompile("CAST(? AS NVARCHAR(4000))", expr)
+			})...))
+	default:
+		return missingDialect(dialect, "CountDistinct")
+	}
+}
+
+// SafeDivide divides a by b and returns NULL if b is 0. Postgres, Sqlite and SQLServer cast a,
+// so that the division of integers isn't truncated.
+func SafeDivide(dialect Dialect, a, b superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile("(CAST(? AS NUMERIC) / NULLIF(?, 0))", a, b)
+	case MySQL, Oracle:
+		return keywordCompile("(? / NULLIF(?, 0))", a, b)
+	case Sqlite:
+		return keywordCompile("(CAST(? AS REAL) / NULLIF(?, 0))", a, b)
+	case SQLServer:
+		return keywordCompile("(CAST(? AS FLOAT) / NULLIF(?, 0))", a, b)
+	default:
+		return missingDialect(dialect, "SafeDivide")
+	}
+}
+
+// Collation is a portable collation for Collate.
+type Collation int
+
+const (
+	// CollationCaseSensitive compares case-sensitively. Postgres compares bytes with the C collation.
+	CollationCaseSensitive Collation = iota
+	// CollationCaseInsensitive compares case-insensitively. Sqlite only folds ASCII characters.
+	CollationCaseInsensitive
+	// CollationBinary compares the bytes or code points.
+	CollationBinary
+)
+
+var collations = map[Dialect]map[Collation]string{
+	Postgres: {
+		CollationCaseSensitive: `"C"`,
+		CollationBinary:        `"C"`,
+	},
+	MySQL: {
+		CollationCaseSensitive:   "utf8mb4_0900_as_cs",
+		CollationCaseInsensitive: "utf8mb4_0900_as_ci",
+		CollationBinary:          "utf8mb4_bin",
+	},
+	Sqlite: {
+		CollationCaseSensitive:   "BINARY",
+		CollationCaseInsensitive: "NOCASE",
+		CollationBinary:          "BINARY",
+	},
+	SQLServer: {
+		CollationCaseSensitive:   "Latin1_General_CS_AS",
+		CollationCaseInsensitive: "Latin1_General_CI_AS",
+		CollationBinary:          "Latin1_General_BIN2",
+	},
+	Oracle: {
+		CollationCaseSensitive:   "BINARY",
+		CollationCaseInsensitive: "BINARY_CI",
+		CollationBinary:          "BINARY",
+	},
+}
+
+// Collate applies collation to expr, e.g. for comparisons. MySQL needs version 8, Oracle version 12.2.
+// Postgres has no case-insensitive collation by default and returns a MissingDialectError
+// for CollationCaseInsensitive.
+func Collate(dialect Dialect, expr superbasic.Expression, collation Collation) superbasic.Expression {
+	name, ok := collations[dialect][collation]
+	if !ok {
+		return missingDialect(dialect, "Collate")
+	}
+
+	return keywordCompile("? COLLATE "+name, expr)
+}
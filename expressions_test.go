@@ -0,0 +1,892 @@
+package esperanto_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+func TestExistsExpr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		expr superbasic.Expression
+		sql  string
+		args []any
+	}{
+		{
+			name: "select one",
+			expr: esperanto.ExistsExpr(superbasic.SQL("SELECT a, ? AS b FROM t WHERE c = ?", "x", 3)),
+			sql:  "EXISTS (SELECT 1 FROM t WHERE c = $1)",
+			args: []any{3},
+		},
+		{
+			name: "not exists",
+			expr: esperanto.NotExistsExpr(superbasic.SQL("SELECT a FROM t WHERE b = ?", 2)),
+			sql:  "NOT EXISTS (SELECT 1 FROM t WHERE b = $1)",
+			args: []any{2},
+		},
+		{
+			name: "keep select list",
+			expr: esperanto.Exists{
+				Subquery:       superbasic.SQL("SELECT a, ? AS b FROM t WHERE c = ?", "x", 3),
+				KeepSelectList: true,
+			},
+			sql:  "EXISTS (SELECT a, $1 AS b FROM t WHERE c = $2)",
+			args: []any{"x", 3},
+		},
+		{
+			name: "select one keeps numbering",
+			expr: superbasic.Compile("a = ? AND ?", superbasic.Value(1),
+				esperanto.ExistsExpr(superbasic.SQL("SELECT ? FROM t WHERE c = ?", "x", 3))),
+			sql:  "a = $1 AND EXISTS (SELECT 1 FROM t WHERE c = $2)",
+			args: []any{1, 3},
+		},
+		{
+			name: "select one keeps aggregates",
+			expr: esperanto.ExistsExpr(superbasic.SQL("SELECT COUNT(*) FROM t WHERE a = ?", 1)),
+			sql:  "EXISTS (SELECT COUNT(*) FROM t WHERE a = $1)",
+			args: []any{1},
+		},
+		{
+			name: "select one keeps set operations",
+			expr: esperanto.ExistsExpr(superbasic.SQL("SELECT a, b FROM t UNION SELECT c, d FROM u")),
+			sql:  "EXISTS (SELECT a, b FROM t UNION SELECT c, d FROM u)",
+		},
+		{
+			name: "select one keeps aliases of HAVING",
+			expr: esperanto.ExistsExpr(superbasic.SQL("SELECT a, b AS x FROM t GROUP BY a, b HAVING x > 1")),
+			sql:  "EXISTS (SELECT a, b AS x FROM t GROUP BY a, b HAVING x > 1)",
+		},
+		{
+			name: "select one keeps aliases of ORDER BY",
+			expr: esperanto.ExistsExpr(superbasic.SQL("SELECT TOP 1 a AS x FROM t ORDER BY x")),
+			sql:  "EXISTS (SELECT TOP 1 a AS x FROM t ORDER BY x)",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			sql, args, err := superbasic.Finalize("$%d", test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sql != test.sql || !reflect.DeepEqual(args, test.args) {
+				t.Errorf("got %s %v, want %s %v", sql, args, test.sql, test.args)
+			}
+		})
+	}
+}
+
+// fails marks a dialect that is expected to return an error in snapshots.
+const fails = "<error>"
+
+// testSnapshot compares the snapshot of executable with want for all dialects of want.
+func testSnapshot(t *testing.T, executable esperanto.Executable, want map[esperanto.Dialect]string) {
+	t.Helper()
+
+	snapshots, errs := esperanto.Snapshot(executable)
+
+	for dialect, sql := range want {
+		if sql == fails {
+			if errs[dialect] == nil {
+				t.Errorf("%s: got %s, want an error", dialect, snapshots[dialect])
+			}
+
+			continue
+		}
+
+		if errs[dialect] != nil {
+			t.Errorf("%s: %s", dialect, errs[dialect])
+
+			continue
+		}
+
+		if snapshots[dialect] != sql {
+			t.Errorf("%s: got %s, want %s", dialect, snapshots[dialect], sql)
+		}
+	}
+}
+
+func TestNot(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Not(dialect, superbasic.SQL("t.flag"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "NOT (t.flag)",
+		esperanto.MySQL:     "NOT (t.flag)",
+		esperanto.Sqlite:    "NOT (t.flag)",
+		esperanto.SQLServer: "(t.flag = 0)",
+		esperanto.Oracle:    "(t.flag = 0)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Not(dialect, superbasic.SQL("a IN (SELECT b FROM T WHERE c = ?)", 1))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "NOT (a IN (SELECT b FROM T WHERE c = $1))",
+		esperanto.SQLServer: "NOT (a IN (SELECT b FROM T WHERE c = @p1))",
+		esperanto.Oracle:    "NOT (a IN (SELECT b FROM T WHERE c = :1))",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Not(dialect, nil)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestPad(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.LPad(dialect, superbasic.SQL("code"), 4, "ab")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "LPAD(code, 4, $1)",
+		esperanto.MySQL:    "LPAD(code, 4, ?)",
+		esperanto.Oracle:   "LPAD(code, 4, :1)",
+		esperanto.SQLServer: "LEFT(REPLICATE(@p1, 4), 4 - (LEN(LEFT(code, 4) + 'x') - 1)) + " +
+			"LEFT(code, 4)",
+		esperanto.Sqlite: "SUBSTR(REPLACE(HEX(ZEROBLOB(4)), '00', ?), 1, MAX(4 - LENGTH(code), 0)) || " +
+			"SUBSTR(code, 1, 4)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.RPad(dialect, superbasic.SQL("code"), 4, "ab")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "RPAD(code, 4, $1)",
+		esperanto.SQLServer: "LEFT(LEFT(code, 4) + REPLICATE(@p1, 4), 4)",
+		esperanto.Sqlite:    "SUBSTR(SUBSTR(code, 1, 4) || REPLACE(HEX(ZEROBLOB(4)), '00', ?), 1, 4)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.LPad(dialect, superbasic.SQL("code"), -1, "ab")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: fails,
+		esperanto.Sqlite:   fails,
+	})
+
+	db := openSqlite(t)
+
+	tests := []struct {
+		pad   func(dialect esperanto.Dialect, expr superbasic.Expression, length int, pad string) superbasic.Expression
+		value string
+		want  string
+	}{
+		{pad: esperanto.LPad, value: "7", want: "aba7"},
+		{pad: esperanto.LPad, value: "", want: "abab"},
+		{pad: esperanto.LPad, value: "123456", want: "1234"},
+		{pad: esperanto.RPad, value: "7", want: "7aba"},
+		{pad: esperanto.RPad, value: "123456", want: "1234"},
+	}
+
+	for _, test := range tests {
+		got, err := scan.One[string](db.QueryRow(context.Background(),
+			superbasic.Compile("SELECT ?", test.pad(esperanto.Sqlite, superbasic.Value(test.value), 4, "ab"))),
+			scan.Any(func(s *string, value string) { *s = value }))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Trim(dialect, superbasic.SQL("name"), "x", esperanto.TrimLeading)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "TRIM(LEADING $1 FROM name)",
+		esperanto.MySQL:     "TRIM(LEADING ? FROM name)",
+		esperanto.SQLServer: "TRIM(LEADING @p1 FROM name)",
+		esperanto.Sqlite:    "LTRIM(name, ?)",
+		esperanto.Oracle:    "LTRIM(name, :1)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Trim(dialect, superbasic.SQL("name"), "", esperanto.TrimBoth)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "TRIM(BOTH $1 FROM name)",
+		esperanto.SQLServer: "LTRIM(RTRIM(name))",
+		esperanto.Oracle:    "LTRIM(RTRIM(name, :1), :2)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Trim(dialect, superbasic.SQL("name"), "x", esperanto.TrimSide("x); DROP TABLE t; --"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.SQLServer: fails,
+		esperanto.Sqlite:    fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestDecimal(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Decimal(dialect, "-12.340")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CAST('-12.340' AS NUMERIC)",
+		esperanto.MySQL:     "CAST(-12.340 AS DECIMAL(5, 3))",
+		esperanto.Sqlite:    "CAST(-12.340 AS DECIMAL(5, 3))",
+		esperanto.SQLServer: "CAST(-12.340 AS DECIMAL(5, 3))",
+		esperanto.Oracle:    "CAST(-12.340 AS NUMBER(5, 3))",
+	})
+
+	for _, invalid := range []string{"-+5", "+-5", "--5", "", ".", "1e5", "1.2.3", "5;"} {
+		invalid := invalid
+
+		testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+			return esperanto.Decimal(dialect, invalid)
+		}, map[esperanto.Dialect]string{esperanto.Postgres: fails, esperanto.MySQL: fails})
+	}
+
+	large := strings.Repeat("9", 42)
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Decimal(dialect, large)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CAST('" + large + "' AS NUMERIC)",
+		esperanto.MySQL:     "CAST(" + large + " AS DECIMAL(42, 0))",
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestJSONSet(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{"address.city", "$.address.city", "{address,city}"} {
+		path := path
+
+		testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+			return esperanto.JSONSet(dialect, "data", path, superbasic.Value("Berlin"))
+		}, map[esperanto.Dialect]string{
+			esperanto.Postgres:  "jsonb_set(data, '{address,city}', $1)",
+			esperanto.MySQL:     "JSON_SET(data, '$.address.city', ?)",
+			esperanto.Sqlite:    "JSON_SET(data, '$.address.city', ?)",
+			esperanto.SQLServer: "JSON_MODIFY(data, '$.address.city', @p1)",
+			esperanto.Oracle:    "JSON_TRANSFORM(data, SET '$.address.city' = :1)",
+		})
+	}
+
+	for _, path := range []string{"", "$.", "{}"} {
+		path := path
+
+		testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+			return esperanto.JSONSet(dialect, "data", path, superbasic.Value("Berlin"))
+		}, map[esperanto.Dialect]string{esperanto.Postgres: fails, esperanto.MySQL: fails, esperanto.SQLServer: fails})
+	}
+}
+
+func TestDateTrunc(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DateTrunc(dialect, esperanto.UnitMonth, superbasic.SQL("created_at"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DATE_TRUNC('month', created_at)",
+		esperanto.MySQL:     "CAST(DATE_FORMAT(created_at, '%Y-%m-01 00:00:00') AS DATETIME)",
+		esperanto.Sqlite:    "strftime('%Y-%m-01 00:00:00', created_at)",
+		esperanto.SQLServer: "DATEADD(month, DATEDIFF(month, 0, created_at), 0)",
+		esperanto.Oracle:    "TRUNC(created_at, 'MM')",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DateTrunc(dialect, esperanto.UnitWeek, superbasic.SQL("created_at"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DATE_TRUNC('week', created_at)",
+		esperanto.MySQL:     "CAST(DATE_SUB(DATE(created_at), INTERVAL WEEKDAY(created_at) DAY) AS DATETIME)",
+		esperanto.Sqlite:    "strftime('%Y-%m-%d 00:00:00', created_at, 'weekday 0', '-6 days')",
+		esperanto.SQLServer: "DATEADD(week, DATEDIFF(week, 0, DATEADD(day, -1, created_at)), 0)",
+		esperanto.Oracle:    "TRUNC(created_at, 'IW')",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DateTrunc(dialect, esperanto.UnitSecond, superbasic.SQL("created_at"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DATE_TRUNC('second', created_at)",
+		esperanto.MySQL:     "CAST(DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s') AS DATETIME)",
+		esperanto.Sqlite:    "strftime('%Y-%m-%d %H:%M:%S', created_at)",
+		esperanto.SQLServer: "DATEADD(second, DATEDIFF(second, '2000-01-01', created_at), CAST('2000-01-01' AS DATETIME2))",
+		esperanto.Oracle:    "CAST(created_at AS DATE)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DateTrunc(dialect, "decade", superbasic.SQL("created_at"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestNullSafeEquals(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.NullSafeEquals(dialect, superbasic.SQL("a"), superbasic.Value(1))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "a IS NOT DISTINCT FROM $1",
+		esperanto.MySQL:     "a <=> ?",
+		esperanto.Sqlite:    "a IS ?",
+		esperanto.SQLServer: "EXISTS (SELECT a INTERSECT SELECT @p1)",
+		esperanto.Oracle:    "DECODE(a, :1, 1, 0) = 1",
+	})
+}
+
+func TestInterval(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Interval(dialect, 3, esperanto.UnitDay)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "INTERVAL '3 day'",
+		esperanto.MySQL:     "INTERVAL 3 DAY",
+		esperanto.Sqlite:    "'+3 days'",
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    "INTERVAL '3' DAY(9)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Interval(dialect, 2, esperanto.UnitWeek)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "INTERVAL '2 week'",
+		esperanto.MySQL:     "INTERVAL 2 WEEK",
+		esperanto.Sqlite:    "'+14 days'",
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    "INTERVAL '14' DAY(9)",
+	})
+}
+
+func TestDateAdd(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DateAdd(dialect, superbasic.SQL("created_at"), 2, esperanto.UnitWeek)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(created_at + INTERVAL '2 week')",
+		esperanto.MySQL:     "DATE_ADD(created_at, INTERVAL 2 WEEK)",
+		esperanto.Sqlite:    "datetime(created_at, '+14 days')",
+		esperanto.SQLServer: "DATEADD(week, 2, created_at)",
+		esperanto.Oracle:    "(created_at + INTERVAL '14' DAY(9))",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DateAdd(dialect, superbasic.SQL("created_at"), -1, esperanto.UnitMonth)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(created_at + INTERVAL '-1 month')",
+		esperanto.MySQL:     "DATE_ADD(created_at, INTERVAL -1 MONTH)",
+		esperanto.Sqlite:    "datetime(created_at, '-1 months')",
+		esperanto.SQLServer: "DATEADD(month, -1, created_at)",
+		esperanto.Oracle:    "(created_at + INTERVAL '-1' MONTH(9))",
+	})
+}
+
+func TestRandomOrder(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.RandomOrder(dialect)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "RANDOM()",
+		esperanto.MySQL:     "RAND()",
+		esperanto.Sqlite:    "RANDOM()",
+		esperanto.SQLServer: "NEWID()",
+		esperanto.Oracle:    "DBMS_RANDOM.VALUE",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.SeededRandomOrder(dialect, 42)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "RANDOM()",
+		esperanto.MySQL:     "RAND(?)",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    "DBMS_RANDOM.VALUE",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.SetRandomSeed(dialect, 0.5)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT setseed($1)",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    "BEGIN DBMS_RANDOM.SEED(:1); END;",
+	})
+}
+
+func TestFullTextMatch(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.FullTextMatch(dialect, []string{"title", "body"}, "go sql", esperanto.FTOptions{FTS5Table: "posts_fts"})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "to_tsvector(COALESCE(title, '') || ' ' || COALESCE(body, '')) @@ plainto_tsquery($1)",
+		esperanto.MySQL:     "MATCH (title, body) AGAINST (? IN NATURAL LANGUAGE MODE)",
+		esperanto.Sqlite:    "posts_fts MATCH ?",
+		esperanto.SQLServer: "FREETEXT((title, body), @p1)",
+		esperanto.Oracle:    "(CONTAINS(title, :1) > 0 OR CONTAINS(body, :2) > 0)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.FullTextMatch(dialect, []string{"title"}, "go sql", esperanto.FTOptions{Language: "english"})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "to_tsvector('english', COALESCE(title, '')) @@ plainto_tsquery('english', $1)",
+		esperanto.MySQL:     "MATCH (title) AGAINST (? IN NATURAL LANGUAGE MODE)",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "FREETEXT((title), @p1, LANGUAGE 'english')",
+		esperanto.Oracle:    "(CONTAINS(title, :1) > 0)",
+	})
+}
+
+func TestBool(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Bool(dialect, true)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "TRUE",
+		esperanto.MySQL:     "TRUE",
+		esperanto.Sqlite:    "TRUE",
+		esperanto.SQLServer: "1",
+		esperanto.Oracle:    "1",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Bool(dialect, false)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FALSE",
+		esperanto.MySQL:     "FALSE",
+		esperanto.Sqlite:    "FALSE",
+		esperanto.SQLServer: "0",
+		esperanto.Oracle:    "0",
+	})
+}
+
+func TestIdentRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	// the escaped ? of the identifier must reach the database as a single ?
+	ident := esperanto.Ident(esperanto.Sqlite, "why?")
+
+	if err := db.Exec(ctx, superbasic.Compile("CREATE TABLE ? (id INTEGER)", ident)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Exec(ctx, superbasic.Compile("INSERT INTO ? (id) VALUES (?)", ident, superbasic.Value(1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := queryInts(t, db, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'why' || CHAR(63)"); !reflect.DeepEqual(got,
+		[]int64{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Table(dialect, "s", "order")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "\"s\".\"order\"",
+		esperanto.MySQL:     "`s`.`order`",
+		esperanto.Sqlite:    "\"s\".\"order\"",
+		esperanto.SQLServer: "[s].[order]",
+		esperanto.Oracle:    "\"S\".\"ORDER\"",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Table(dialect, "", `we"ird]`+"`?")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "\"we\"\"ird]`?\"",
+		esperanto.MySQL:     "`we\"ird]``?`",
+		esperanto.Sqlite:    "\"we\"\"ird]`?\"",
+		esperanto.SQLServer: "[we\"ird]]`?]",
+		esperanto.Oracle:    "\"WE\"\"IRD]`?\"",
+	})
+}
+
+func TestRound(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Round(dialect, superbasic.SQL("price"), 2)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ROUND(CAST(price AS NUMERIC), 2)",
+		esperanto.MySQL:     "ROUND(price, 2)",
+		esperanto.Sqlite:    "ROUND(price, 2)",
+		esperanto.SQLServer: "ROUND(price, 2)",
+		esperanto.Oracle:    "ROUND(price, 2)",
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Truncate(dialect, superbasic.SQL("price"), 2)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "TRUNC(CAST(price AS NUMERIC), 2)",
+		esperanto.MySQL:     "TRUNCATE(price, 2)",
+		esperanto.Sqlite:    "(CAST(price * 100.0 AS INTEGER) / 100.0)",
+		esperanto.SQLServer: "ROUND(price, 2, 1)",
+		esperanto.Oracle:    "TRUNC(price, 2)",
+	})
+}
+
+func TestToJSON(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ToJSON(dialect, superbasic.SQL("doc"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CAST(doc AS JSONB)",
+		esperanto.MySQL:     "CAST(doc AS JSON)",
+		esperanto.Sqlite:    "json(doc)",
+		esperanto.SQLServer: "JSON_QUERY(doc)",
+		esperanto.Oracle:    "TREAT(doc AS JSON)",
+	})
+}
+
+func TestIsValidJSON(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.IsValidJSON(dialect, superbasic.SQL("doc"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "doc IS JSON",
+		esperanto.MySQL:     "JSON_VALID(doc) = 1",
+		esperanto.Sqlite:    "json_valid(doc) = 1",
+		esperanto.SQLServer: "ISJSON(doc) = 1",
+		esperanto.Oracle:    "doc IS JSON",
+	})
+}
+
+func TestOverIgnoreNulls(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Over(dialect, superbasic.SQL("LAST_VALUE(price)"), esperanto.Window{
+			PartitionBy: []string{"product"},
+			OrderBy:     []esperanto.OrderItem{{Column: "day"}},
+			Frame:       "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW",
+			IgnoreNulls: true,
+		})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: fails,
+		esperanto.MySQL:    fails,
+		esperanto.Sqlite:   fails,
+		esperanto.SQLServer: "LAST_VALUE(price) IGNORE NULLS OVER (PARTITION BY product ORDER BY day ROWS BETWEEN UNBOUNDED " +
+			"PRECEDING AND CURRENT ROW)",
+		esperanto.Oracle: "LAST_VALUE(price) IGNORE NULLS OVER (PARTITION BY product ORDER BY day ROWS BETWEEN UNBOUNDED " +
+			"PRECEDING AND CURRENT ROW)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Over(dialect, superbasic.SQL("LAST_VALUE(price)"), esperanto.Window{PartitionBy: []string{"product"}})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "LAST_VALUE(price) OVER (PARTITION BY product)",
+		esperanto.MySQL:     "LAST_VALUE(price) OVER (PARTITION BY product)",
+		esperanto.Sqlite:    "LAST_VALUE(price) OVER (PARTITION BY product)",
+		esperanto.SQLServer: "LAST_VALUE(price) OVER (PARTITION BY product)",
+		esperanto.Oracle:    "LAST_VALUE(price) OVER (PARTITION BY product)",
+	})
+}
+
+func TestCurrentUser(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CurrentUser(dialect)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "CURRENT_USER",
+		esperanto.MySQL:     "CURRENT_USER()",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "SUSER_SNAME()",
+		esperanto.Oracle:    "USER",
+	})
+}
+
+func TestToEpoch(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ToEpoch(dialect, superbasic.SQL("created_at"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "EXTRACT(EPOCH FROM created_at)",
+		esperanto.MySQL:     "UNIX_TIMESTAMP(created_at)",
+		esperanto.Sqlite:    "CAST(strftime('%s', created_at) AS INTEGER)",
+		esperanto.SQLServer: "DATEDIFF_BIG(second, '1970-01-01', created_at)",
+		esperanto.Oracle:    "ROUND((CAST(created_at AS DATE) - DATE '1970-01-01') * 86400)",
+	})
+}
+
+func TestFromEpoch(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.FromEpoch(dialect, superbasic.Value(1700000000))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "to_timestamp($1)",
+		esperanto.MySQL:     "FROM_UNIXTIME(?)",
+		esperanto.Sqlite:    "datetime(?, 'unixepoch')",
+		esperanto.SQLServer: "DATEADD(second, @p1, '1970-01-01')",
+		esperanto.Oracle:    "(TIMESTAMP '1970-01-01 00:00:00' + NUMTODSINTERVAL(:1, 'SECOND'))",
+	})
+}
+
+func TestAtTimeZone(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.AtTimeZone(dialect, superbasic.SQL("created_at"), "Europe/Berlin")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(created_at AT TIME ZONE 'Europe/Berlin')",
+		esperanto.MySQL:     "CONVERT_TZ(created_at, @@session.time_zone, 'Europe/Berlin')",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "(created_at AT TIME ZONE 'Europe/Berlin')",
+		esperanto.Oracle:    "(created_at AT TIME ZONE 'Europe/Berlin')",
+	})
+}
+
+func TestFragments(t *testing.T) {
+	t.Parallel()
+
+	fragments := esperanto.Fragments{}
+
+	// the fragment is referenced before it is defined
+	query := superbasic.Compile("SELECT * FROM t WHERE ? OR (? AND b = ?)",
+		fragments.Ref("active"), fragments.Ref("active"), superbasic.Value(3))
+
+	fragments.Fragment("active", superbasic.SQL("a BETWEEN ? AND ?", 1, 2))
+
+	sql, args, err := esperanto.Finalize(esperanto.Placeholders("$%d"), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "SELECT * FROM t WHERE a BETWEEN $1 AND $2 OR (a BETWEEN $3 AND $4 AND b = $5)"; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+
+	if want := []any{1, 2, 1, 2, 3}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got %v, want %v", args, want)
+	}
+
+	if _, _, err = esperanto.Finalize(esperanto.Placeholders("?"), fragments.Ref("missing")); err == nil {
+		t.Error("got no error for an unknown fragment")
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BitAnd(dialect, superbasic.SQL("flags"), superbasic.Value(4))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(flags & $1)",
+		esperanto.MySQL:     "(flags & ?)",
+		esperanto.Sqlite:    "(flags & ?)",
+		esperanto.SQLServer: "(flags & @p1)",
+		esperanto.Oracle:    "BITAND(flags, :1)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BitOr(dialect, superbasic.SQL("flags"), superbasic.Value(4))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(flags | $1)",
+		esperanto.MySQL:     "(flags | ?)",
+		esperanto.Sqlite:    "(flags | ?)",
+		esperanto.SQLServer: "(flags | @p1)",
+		esperanto.Oracle:    "(flags + :1 - BITAND(flags, :2))",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BitXor(dialect, superbasic.SQL("flags"), superbasic.Value(4))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(flags # $1)",
+		esperanto.MySQL:     "(flags ^ ?)",
+		esperanto.Sqlite:    "((flags | ?) - (flags & ?))",
+		esperanto.SQLServer: "(flags ^ @p1)",
+		esperanto.Oracle:    "(flags + :1 - 2 * BITAND(flags, :2))",
+	})
+}
+
+func TestMod(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Mod(dialect, superbasic.SQL("a"), superbasic.Value(3))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(a % $1)",
+		esperanto.MySQL:     "(a % ?)",
+		esperanto.Sqlite:    "(a % ?)",
+		esperanto.SQLServer: "(a % @p1)",
+		esperanto.Oracle:    "MOD(a, :1)",
+	})
+}
+
+func TestIntDiv(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.IntDiv(dialect, superbasic.SQL("a"), superbasic.Value(3))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DIV(CAST(a AS NUMERIC), CAST($1 AS NUMERIC))",
+		esperanto.MySQL:     "(a DIV ?)",
+		esperanto.Sqlite:    "CAST(a / ? AS INTEGER)",
+		esperanto.SQLServer: "CAST(a / @p1 AS BIGINT)",
+		esperanto.Oracle:    "TRUNC(a / :1)",
+	})
+}
+
+func TestPosition(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Position(dialect, superbasic.Value("@"), superbasic.SQL("email"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "STRPOS(email, $1)",
+		esperanto.MySQL:     "LOCATE(?, email)",
+		esperanto.Sqlite:    "INSTR(email, ?)",
+		esperanto.SQLServer: "CHARINDEX(@p1, email)",
+		esperanto.Oracle:    "INSTR(email, :1)",
+	})
+}
+
+func TestSumOrZero(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.SumOrZero(superbasic.SQL("amount"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "COALESCE(SUM(amount), 0)",
+		esperanto.MySQL:     "COALESCE(SUM(amount), 0)",
+		esperanto.Sqlite:    "COALESCE(SUM(amount), 0)",
+		esperanto.SQLServer: "COALESCE(SUM(amount), 0)",
+		esperanto.Oracle:    "COALESCE(SUM(amount), 0)",
+	})
+}
+
+func TestAvgOrNull(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.AvgOrNull(dialect, superbasic.SQL("amount"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "AVG(amount)",
+		esperanto.MySQL:     "AVG(amount)",
+		esperanto.Sqlite:    "AVG(amount)",
+		esperanto.SQLServer: "AVG(CAST(amount AS FLOAT))",
+		esperanto.Oracle:    "AVG(amount)",
+	})
+}
+
+func TestCountDistinct(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CountDistinct(dialect, superbasic.SQL("a"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "COUNT(DISTINCT a)",
+		esperanto.MySQL:     "COUNT(DISTINCT a)",
+		esperanto.Sqlite:    "COUNT(DISTINCT a)",
+		esperanto.SQLServer: "COUNT(DISTINCT a)",
+		esperanto.Oracle:    "COUNT(DISTINCT a)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CountDistinct(dialect, superbasic.SQL("a"), superbasic.SQL("b"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "COUNT(DISTINCT (a, b))",
+		esperanto.MySQL:     "COUNT(DISTINCT a, b)",
+		esperanto.Sqlite:    "COUNT(DISTINCT a || '|' || b)",
+		esperanto.SQLServer: "COUNT(DISTINCT CAST(a AS NVARCHAR(4000)) + '|' + CAST(b AS NVARCHAR(4000)))",
+		esperanto.Oracle:    "COUNT(DISTINCT a || '|' || b)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.CountDistinct(dialect)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestSafeDivide(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.SafeDivide(dialect, superbasic.SQL("a"), superbasic.SQL("b"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "(CAST(a AS NUMERIC) / NULLIF(b, 0))",
+		esperanto.MySQL:     "(a / NULLIF(b, 0))",
+		esperanto.Sqlite:    "(CAST(a AS REAL) / NULLIF(b, 0))",
+		esperanto.SQLServer: "(CAST(a AS FLOAT) / NULLIF(b, 0))",
+		esperanto.Oracle:    "(a / NULLIF(b, 0))",
+	})
+}
+
+func TestCollate(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Collate(dialect, superbasic.SQL("name"), esperanto.CollationCaseSensitive)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "name COLLATE \"C\"",
+		esperanto.MySQL:     "name COLLATE utf8mb4_0900_as_cs",
+		esperanto.Sqlite:    "name COLLATE BINARY",
+		esperanto.SQLServer: "name COLLATE Latin1_General_CS_AS",
+		esperanto.Oracle:    "name COLLATE BINARY",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Collate(dialect, superbasic.SQL("name"), esperanto.CollationCaseInsensitive)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     "name COLLATE utf8mb4_0900_as_ci",
+		esperanto.Sqlite:    "name COLLATE NOCASE",
+		esperanto.SQLServer: "name COLLATE Latin1_General_CI_AS",
+		esperanto.Oracle:    "name COLLATE BINARY_CI",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Collate(dialect, superbasic.SQL("name"), esperanto.CollationBinary)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "name COLLATE \"C\"",
+		esperanto.MySQL:     "name COLLATE utf8mb4_bin",
+		esperanto.Sqlite:    "name COLLATE BINARY",
+		esperanto.SQLServer: "name COLLATE Latin1_General_BIN2",
+		esperanto.Oracle:    "name COLLATE BINARY",
+	})
+}
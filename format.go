@@ -0,0 +1,242 @@
+package esperanto
+
+import (
+	"strings"
+
+	"github.com/wroge/superbasic"
+)
+
+var keywords = map[string]bool{
+	"ADD": true, "ALL": true, "ALTER": true, "AND": true, "ANY": true, "APPLY": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASCADE": true, "CASE": true, "CHECK": true, "COLLATE": true, "COLUMN": true,
+	"CONFLICT": true, "CONSTRAINT": true, "CREATE": true, "CROSS": true, "DEFAULT": true, "DELETE": true,
+	"DESC": true, "DISTINCT": true, "DO": true, "DROP": true, "ELSE": true, "END": true, "EXCEPT": true,
+	"EXISTS": true, "FALSE": true, "FETCH": true, "FIRST": true, "FOR": true, "FOREIGN": true, "FROM": true,
+	"FULL": true, "GROUP": true, "HAVING": true, "IF": true, "IN": true, "INDEX": true, "INNER": true,
+	"INSERT": true, "INTERSECT": true, "INTO": true, "IS": true, "JOIN": true, "KEY": true, "LAST": true, "LATERAL": true,
+	"LEFT": true, "LIKE": true, "LIMIT": true, "LOCKED": true, "MATCHED": true, "MERGE": true, "NEXT": true,
+	"NOT": true, "NOTHING": true, "NOWAIT": true, "NULL": true, "NULLS": true, "OFFSET": true, "ON": true,
+	"ONLY": true, "OR": true, "ORDER": true, "OUTER": true, "OVER": true, "PARTITION": true, "PRIMARY": true,
+	"RECURSIVE": true, "REFERENCES": true, "RESTRICT": true, "RETURNING": true, "RIGHT": true, "ROWS": true,
+	"SELECT": true, "SET": true, "SKIP": true, "TABLE": true, "THEN": true, "TIES": true, "TOP": true,
+	"TRUE": true, "UNION": true, "UNIQUE": true, "UPDATE": true, "USING": true, "VALUES": true, "WHEN": true,
+	"WHERE": true, "WITH": true,
+}
+
+// KeywordCasing is the case of keywords.
+type KeywordCasing int
+
+const (
+	KeywordAsWritten KeywordCasing = iota
+	KeywordUpper
+	KeywordLower
+)
+
+// KeywordCase is the case of the keywords in the SQL of the builders of this package, e.g. UpdateFrom.
+// It is applied when an expression is finalized. Raw SQL, like the filter of a builder, is left untouched.
+var KeywordCase = KeywordAsWritten
+
+// caseKeywords applies KeywordCase to the keywords of sql.
+func caseKeywords(sql string) string {
+	return withKeywordCase(sql, KeywordCase)
+}
+
+// withKeywordCase applies casing to the keywords of sql.
+func withKeywordCase(sql string, casing KeywordCasing) string {
+	if casing != KeywordUpper && casing != KeywordLower {
+		return sql
+	}
+
+	tokens := lex(sql)
+
+	for i, t := range tokens {
+		if t.kind != tokenWord || !keywords[strings.ToUpper(t.text)] {
+			continue
+		}
+
+		if casing == KeywordLower {
+			tokens[i].text = strings.ToLower(t.text)
+		} else {
+			tokens[i].text = strings.ToUpper(t.text)
+		}
+	}
+
+	return join(tokens)
+}
+
+// keywordSQL is superbasic.SQL for the SQL of builders.
+func keywordSQL(sql string, args ...any) superbasic.Expression {
+	return deferred(func() superbasic.Expression {
+		return superbasic.SQL(caseKeywords(sql), args...)
+	})
+}
+
+// keywordCompile is superbasic.Compile for the templates of builders.
+func keywordCompile(template string, expressions ...superbasic.Expression) superbasic.Expression {
+	return deferred(func() superbasic.Expression {
+		return superbasic.Compile(caseKeywords(template), expressions...)
+	})
+}
+
+// keywordJoin is superbasic.Join for separators of builders, e.g. " OR ".
+func keywordJoin(sep string, expressions ...superbasic.Expression) superbasic.Expression {
+	return deferred(func() superbasic.Expression {
+		return superbasic.Join(caseKeywords(sep), expressions...)
+	})
+}
+
+// deferred builds its expression when it is finalized, so that KeywordCase is applied at that time.
+type deferred func() superbasic.Expression
+
+func (d deferred) ToSQL() (string, []any, error) {
+	expression := d()
+	if expression == nil {
+		return "", nil, superbasic.ExpressionError{}
+	}
+
+	return expression.ToSQL()
+}
+
+// clauses start a new line at the top level of a statement.
+var clauses = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "FETCH": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "VALUES": true, "SET": true,
+	"RETURNING": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "FULL": true, "CROSS": true,
+	"USING": true, "OUTPUT": true,
+}
+
+// joinModifiers keep a following JOIN or ON on the same line.
+var joinModifiers = map[string]bool{
+	"LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "FULL": true, "CROSS": true, "NATURAL": true,
+	"LATERAL": true, "DO": true, "CONFLICT": true, "UPDATE": true, "DELETE": true, "EXCEPT": true,
+}
+
+// Format finalizes expr with the placeholder of dialect and lays the SQL out for human readable files.
+// Keywords are uppercased, top-level clauses start on a new line and column definitions of CREATE TABLE
+// are indented. The output is deterministic, arguments are left as placeholders.
+func Format(dialect Dialect, expr superbasic.Expression) (string, error) {
+	sql, _, err := Finalize(Placeholders(dialect.Placeholder()), expr)
+	if err != nil {
+		return "", err
+	}
+
+	return format(lex(sql)), nil
+}
+
+//nolint:cyclop,funlen
+func format(tokens []token) string {
+	var (
+		builder  = &strings.Builder{}
+		lists    []bool
+		space    bool
+		fresh    bool
+		previous token
+		create   bool
+	)
+
+	newline := func() {
+		builder.WriteString("\n" + strings.Repeat("\t", len(lists)))
+
+		space = false
+		fresh = true
+	}
+
+	inList := func() bool {
+		return len(lists) > 0 && lists[len(lists)-1]
+	}
+
+	for i, t := range tokens {
+		if t.kind == tokenSpace {
+			space = builder.Len() > 0 && !fresh
+
+			continue
+		}
+
+		lineStart := fresh
+		fresh = false
+
+		if t.kind == tokenWord && keywords[strings.ToUpper(t.text)] {
+			t.text = strings.ToUpper(t.text)
+		}
+
+		switch {
+		case t.text == "(":
+			if space {
+				builder.WriteString(" ")
+			}
+
+			builder.WriteString("(")
+
+			lists = append(lists, create && previous.kind != tokenSymbol)
+			create = false
+			space = false
+
+			if inList() {
+				newline()
+			}
+		case t.text == ")":
+			list := inList()
+
+			if len(lists) > 0 {
+				lists = lists[:len(lists)-1]
+			}
+
+			if list {
+				newline()
+			}
+
+			builder.WriteString(")")
+		case t.text == "," && inList():
+			builder.WriteString(",")
+			newline()
+		case t.kind == tokenWord && len(lists) == 0 && builder.Len() > 0 && clauses[t.text] &&
+			!joinModifiers[previous.text] && !isFunctionCall(tokens[i+1:]):
+			if !lineStart {
+				newline()
+			}
+
+			builder.WriteString(t.text)
+
+			fresh = false
+		default:
+			if space {
+				builder.WriteString(" ")
+			}
+
+			builder.WriteString(t.text)
+			space = false
+		}
+
+		switch {
+		case t.text == "TABLE" && (previous.text == "CREATE" || isKeyword(previous, "TEMPORARY") ||
+			isKeyword(previous, "TEMP")):
+			create = true
+		case t.text == "AS" || t.text == "SELECT":
+			create = false
+		}
+
+		// a line comment runs to the end of the line and would comment out the rest of the statement
+		if t.kind == tokenComment && strings.HasPrefix(t.text, "--") && i < len(tokens)-1 {
+			newline()
+		}
+
+		if t.kind != tokenComment {
+			previous = t
+		}
+	}
+
+	return builder.String()
+}
+
+// isFunctionCall reports if the next significant token opens a parenthesis, e.g. LEFT(s, 1).
+func isFunctionCall(tokens []token) bool {
+	for _, t := range tokens {
+		if t.kind == tokenSpace {
+			return false
+		}
+
+		return t.text == "("
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package esperanto_test
+
+import (
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+//nolint:paralleltest
+func TestKeywordCase(t *testing.T) {
+	defer func() { esperanto.KeywordCase = esperanto.KeywordAsWritten }()
+
+	update := esperanto.UpdateFrom(esperanto.Postgres, "t",
+		[]esperanto.Assignment{{Column: "a", Value: superbasic.Value(1)}},
+		superbasic.SQL("u"), superbasic.SQL("t.id = u.id AND u.name IN (SELECT name FROM x)"))
+	table := esperanto.Table(esperanto.SQLServer, "dbo", "order")
+	isNull := esperanto.IsNull(esperanto.SQLServer, "Key")
+
+	tests := []struct {
+		keywordCase esperanto.KeywordCasing
+		expr        superbasic.Expression
+		sql         string
+	}{
+		{
+			keywordCase: esperanto.KeywordLower,
+			expr:        update,
+			sql:         "update t set a = $1 from u where t.id = u.id AND u.name IN (SELECT name FROM x)",
+		},
+		{
+			keywordCase: esperanto.KeywordUpper,
+			expr:        update,
+			sql:         "UPDATE t SET a = $1 FROM u WHERE t.id = u.id AND u.name IN (SELECT name FROM x)",
+		},
+		{keywordCase: esperanto.KeywordUpper, expr: table, sql: "[dbo].[order]"},
+		{keywordCase: esperanto.KeywordLower, expr: isNull, sql: "[Key] is null"},
+		{keywordCase: esperanto.KeywordAsWritten, expr: isNull, sql: "[Key] IS NULL"},
+	}
+
+	for _, test := range tests {
+		// the case is applied when the expression is finalized, not when it is built
+		esperanto.KeywordCase = test.keywordCase
+
+		sql, _, err := superbasic.Finalize("$%d", test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sql != test.sql {
+			t.Errorf("got %s, want %s", sql, test.sql)
+		}
+	}
+}
+
+//nolint:paralleltest
+func TestKeywordCaseRawSQL(t *testing.T) {
+	defer func() { esperanto.KeywordCase = esperanto.KeywordAsWritten }()
+
+	esperanto.KeywordCase = esperanto.KeywordLower
+
+	sql, _, err := superbasic.Finalize("?", esperanto.Not(esperanto.SQLServer, superbasic.SQL("a IN (SELECT b FROM T)")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "not (a IN (SELECT b FROM T))"; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr superbasic.Expression
+		sql  string
+	}{
+		{
+			expr: esperanto.CreateTableIfNotExists(esperanto.Postgres, "users", superbasic.SQL(
+				"id integer primary key, name text not null, created_at timestamp default current_timestamp, "+
+					"unique (name)")),
+			sql: "CREATE TABLE IF NOT EXISTS users (\n\tid integer PRIMARY KEY,\n\tname text NOT NULL,\n" +
+				"\tcreated_at timestamp DEFAULT current_timestamp,\n\tUNIQUE (name)\n)",
+		},
+		{
+			// a line comment ends its line, otherwise the rest of the statement is commented out
+			expr: superbasic.SQL("select id, -- the id\nname from users -- all users\nwhere id = ? /* one */", 1),
+			sql:  "SELECT id, -- the id\nname\nFROM users -- all users\nWHERE id = $1 /* one */",
+		},
+	}
+
+	for _, test := range tests {
+		sql, err := esperanto.Format(esperanto.Postgres, test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sql != test.sql {
+			t.Errorf("got %q, want %q", sql, test.sql)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package esperanto
+
+import "strings"
+
+type tokenKind int
+
+const (
+	tokenSpace tokenKind = iota
+	tokenWord
+	tokenString
+	tokenIdentifier
+	tokenComment
+	tokenPlaceholder
+	tokenEscaped
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits sql into tokens, so that placeholders and keywords inside
+// string literals, quoted identifiers and comments can be told apart.
+func lex(sql string) []token {
+	var tokens []token
+
+	for len(sql) > 0 {
+		kind, length := next(sql)
+
+		tokens = append(tokens, token{kind: kind, text: sql[:length]})
+		sql = sql[length:]
+	}
+
+	return tokens
+}
+
+//nolint:cyclop
+func next(sql string) (tokenKind, int) {
+	switch char := sql[0]; {
+	case char == '?':
+		if len(sql) > 1 && sql[1] == '?' {
+			return tokenEscaped, 2
+		}
+
+		return tokenPlaceholder, 1
+	case char == '\'':
+		return tokenString, quoted(sql, '\'')
+	case char == '"' || char == '`':
+		return tokenIdentifier, quoted(sql, char)
+	case char == '[':
+		// SQLServer identifiers like [order], but not Postgres subscripts like ARRAY[?]
+		length := quoted(sql, ']')
+		if sql[length-1] == ']' && !strings.ContainsAny(sql[1:length-1], "?[") {
+			return tokenIdentifier, length
+		}
+
+		return tokenSymbol, 1
+	case strings.HasPrefix(sql, "--"):
+		if index := strings.IndexByte(sql, '\n'); index >= 0 {
+			return tokenComment, index
+		}
+
+		return tokenComment, len(sql)
+	case strings.HasPrefix(sql, "/*"):
+		if index := strings.Index(sql[2:], "*/"); index >= 0 {
+			return tokenComment, index + 4
+		}
+
+		return tokenComment, len(sql)
+	case isSpace(char):
+		return tokenSpace, span(sql, isSpace)
+	case isWord(char):
+		return tokenWord, span(sql, isWord)
+	default:
+		return tokenSymbol, 1
+	}
+}
+
+// quoted returns the length of a quoted token including doubled quotes as escapes.
+func quoted(sql string, quote byte) int {
+	for i := 1; i < len(sql); i++ {
+		if sql[i] != quote {
+			continue
+		}
+
+		if i+1 < len(sql) && sql[i+1] == quote {
+			i++
+
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(sql)
+}
+
+func span(sql string, fn func(byte) bool) int {
+	for i := 0; i < len(sql); i++ {
+		if !fn(sql[i]) {
+			return i
+		}
+	}
+
+	return len(sql)
+}
+
+func isSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
+}
+
+func isWord(char byte) bool {
+	return char == '_' || char == '$' || char == '#' || char == '@' ||
+		('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z') || ('0' <= char && char <= '9') ||
+		char >= 0x80
+}
+
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func join(tokens []token) string {
+	builder := &strings.Builder{}
+
+	for _, t := range tokens {
+		builder.WriteString(t.text)
+	}
+
+	return builder.String()
+}
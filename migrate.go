@@ -0,0 +1,182 @@
+//nolint:wrapcheck
+package esperanto
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+// Migration is a named list of statements that is applied once.
+type Migration struct {
+	ID         string
+	Statements []Executable
+}
+
+// ChecksumError is returned by Migrate if an applied migration has been changed.
+type ChecksumError struct {
+	ID string
+}
+
+func (e ChecksumError) Error() string {
+	return fmt.Sprintf("wroge/esperanto error: checksum of applied migration '%s' changed", e.ID)
+}
+
+// Migrate applies the pending migrations in order and returns the IDs of the applied migrations.
+// Applied migrations are tracked with a checksum of their SQL in the table schema_migrations.
+// The checksum ignores the case of keywords, so changing KeywordCase doesn't invalidate applied migrations.
+// Each migration runs in its own transaction, except on MySQL, which commits DDL implicitly.
+// A migration is recorded before its statements run, so a concurrent Migrate fails on the
+// primary key of schema_migrations instead of applying the migration twice.
+func Migrate(ctx context.Context, db DB, dialect Dialect, migrations []Migration) ([]string, error) {
+	err := db.Exec(ctx, CreateTableIfNotExists(dialect, "schema_migrations",
+		superbasic.SQL("id VARCHAR(255) PRIMARY KEY,\n\tchecksum VARCHAR(64) NOT NULL")))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(ctx, superbasic.SQL("SELECT id, checksum FROM schema_migrations"))
+	if err != nil {
+		return nil, err
+	}
+
+	type applied struct {
+		id       string
+		checksum string
+	}
+
+	all, err := scan.All[applied](rows,
+		scan.Any(func(a *applied, id string) { a.id = id }),
+		scan.Any(func(a *applied, checksum string) { a.checksum = checksum }),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(all))
+
+	for _, a := range all {
+		checksums[a.id] = a.checksum
+	}
+
+	var ids []string
+
+	for _, migration := range migrations {
+		checksum, err := migrationChecksum(dialect, migration)
+		if err != nil {
+			return ids, err
+		}
+
+		if previous, ok := checksums[migration.ID]; ok {
+			if previous != checksum {
+				return ids, ChecksumError{ID: migration.ID}
+			}
+
+			continue
+		}
+
+		if err = applyMigration(ctx, db, dialect, migration, checksum); err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, migration.ID)
+	}
+
+	return ids, nil
+}
+
+func migrationChecksum(dialect Dialect, migration Migration) (string, error) {
+	hash := sha256.New()
+
+	for _, statement := range migration.Statements {
+		expression := statement(dialect)
+		if skipped(expression) {
+			continue
+		}
+
+		sql, args, err := superbasic.Finalize(dialect.Placeholder(), expression)
+		if err != nil {
+			return "", err
+		}
+
+		values := make([]string, len(args))
+
+		for i, arg := range args {
+			if values[i], err = canonicalValue(arg); err != nil {
+				return "", err
+			}
+		}
+
+		// the keywords are uppercased, so that KeywordCase doesn't change the checksum
+		fmt.Fprintf(hash, "%s\n[%s]\n", withKeywordCase(sql, KeywordUpper), strings.Join(values, " "))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// canonicalValue encodes the driver value of arg, so that pointers and Valuers
+// are hashed by their value instead of their address.
+func canonicalValue(arg any) (string, error) {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return "", err
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return strconv.Quote(value), nil
+	case []byte:
+		return "0x" + hex.EncodeToString(value), nil
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano), nil
+	default:
+		return fmt.Sprintf("%T(%v)", value, value), nil
+	}
+}
+
+func applyMigration(ctx context.Context, db DB, dialect Dialect, migration Migration, checksum string) error {
+	record := superbasic.SQL("INSERT INTO schema_migrations (id, checksum) VALUES (?, ?)", migration.ID, checksum)
+
+	if dialect == MySQL {
+		if err := db.Exec(ctx, record); err != nil {
+			return err
+		}
+
+		for _, statement := range migration.Statements {
+			if expression := statement(dialect); !skipped(expression) {
+				if err := db.Exec(ctx, expression); err != nil {
+					return forgetMigration(ctx, db, migration.ID, err)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	statements := append([]Executable{func(Dialect) superbasic.Expression { return record }},
+		migration.Statements...)
+
+	return Exec(ctx, db, dialect, statements...)
+}
+
+// forgetMigration removes the record of a failed migration without transaction, so that it runs again.
+func forgetMigration(ctx context.Context, db DB, id string, err error) error {
+	if deleteErr := db.Exec(ctx, superbasic.SQL("DELETE FROM schema_migrations WHERE id = ?", id)); deleteErr != nil {
+		return RollbackError{
+			Err:  deleteErr,
+			Wrap: err,
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,83 @@
+package esperanto_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	migrations := func(name string) []esperanto.Migration {
+		return []esperanto.Migration{
+			{ID: "1", Statements: []esperanto.Executable{func(esperanto.Dialect) superbasic.Expression {
+				return superbasic.SQL("CREATE TABLE t (name TEXT)")
+			}}},
+			{ID: "2", Statements: []esperanto.Executable{func(esperanto.Dialect) superbasic.Expression {
+				// a new pointer on every call must not change the checksum
+				return superbasic.SQL("INSERT INTO t (name) VALUES (?)", &name)
+			}}},
+		}
+	}
+
+	ids, err := esperanto.Migrate(ctx, db, esperanto.Sqlite, migrations("a"))
+	if err != nil || !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Fatalf("got %v %v, want [1 2]", ids, err)
+	}
+
+	ids, err = esperanto.Migrate(ctx, db, esperanto.Sqlite, migrations("a"))
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("got %v %v, want no migrations", ids, err)
+	}
+
+	_, err = esperanto.Migrate(ctx, db, esperanto.Sqlite, migrations("b"))
+	if !errors.As(err, &esperanto.ChecksumError{}) {
+		t.Errorf("got %v, want a ChecksumError", err)
+	}
+
+	failing := append(migrations("a"), esperanto.Migration{ID: "3", Statements: []esperanto.Executable{
+		func(esperanto.Dialect) superbasic.Expression { return superbasic.SQL("INSERT INTO missing VALUES (1)") },
+	}})
+
+	if _, err = esperanto.Migrate(ctx, db, esperanto.Sqlite, failing); err == nil {
+		t.Fatal("got no error for a failing migration")
+	}
+
+	if count := queryInts(t, db, "SELECT COUNT(*) FROM schema_migrations WHERE id = '3'"); count[0] != 0 {
+		t.Error("failing migration has been recorded")
+	}
+}
+
+//nolint:paralleltest
+func TestMigrateKeywordCase(t *testing.T) {
+	defer func() { esperanto.KeywordCase = esperanto.KeywordAsWritten }()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	migrations := []esperanto.Migration{
+		{ID: "1", Statements: []esperanto.Executable{func(dialect esperanto.Dialect) superbasic.Expression {
+			return esperanto.CreateTableIfNotExists(dialect, "t", superbasic.SQL("id INTEGER"))
+		}}},
+	}
+
+	if _, err := esperanto.Migrate(ctx, db, esperanto.Sqlite, migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	// the builders render lowercase keywords now, which must not change the checksum
+	esperanto.KeywordCase = esperanto.KeywordLower
+
+	ids, err := esperanto.Migrate(ctx, db, esperanto.Sqlite, migrations)
+	if err != nil || len(ids) != 0 {
+		t.Errorf("got %v %v, want no migrations", ids, err)
+	}
+}
@@ -0,0 +1,103 @@
+//nolint:ireturn
+package esperanto
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+// NoHandleError is returned by a MultiDB for a dialect without handle.
+type NoHandleError struct {
+	Dialect Dialect
+}
+
+func (e NoHandleError) Error() string {
+	return fmt.Sprintf("wroge/esperanto error: no handle for dialect '%s'", e.Dialect)
+}
+
+// MultiDB routes to one database handle per dialect.
+// Use For to run Query and its variants against the handle of a dialect.
+type MultiDB struct {
+	Handles map[Dialect]DB
+}
+
+// For returns the handle of dialect. Without handle the returned DB fails with a NoHandleError.
+func (m MultiDB) For(dialect Dialect) DB {
+	if db, ok := m.Handles[dialect]; ok {
+		return db
+	}
+
+	return errDB{err: NoHandleError{Dialect: dialect}}
+}
+
+// Exec runs the executables in a transaction on the handle of dialect.
+func (m MultiDB) Exec(ctx context.Context, dialect Dialect, executables ...Executable) error {
+	return Exec(ctx, m.For(dialect), dialect, executables...)
+}
+
+// CloseError is returned by MultiDB.Close with the errors of the handles that failed to close.
+type CloseError struct {
+	Errors map[Dialect]error
+}
+
+func (e CloseError) Error() string {
+	dialects := make([]string, 0, len(e.Errors))
+	for dialect := range e.Errors {
+		dialects = append(dialects, string(dialect))
+	}
+
+	sort.Strings(dialects)
+
+	messages := make([]string, len(dialects))
+	for i, dialect := range dialects {
+		messages[i] = fmt.Sprintf("%s: %s", dialect, e.Errors[Dialect(dialect)])
+	}
+
+	return fmt.Sprintf("wroge/esperanto error: closing handles: %s", strings.Join(messages, "; "))
+}
+
+// Close closes all handles, even if some fail, and returns a CloseError with the errors of the failed handles.
+func (m MultiDB) Close() error {
+	errs := map[Dialect]error{}
+
+	for dialect, db := range m.Handles {
+		if err := db.Close(); err != nil {
+			errs[dialect] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return CloseError{Errors: errs}
+	}
+
+	return nil
+}
+
+type errDB struct {
+	err error
+}
+
+func (e errDB) Close() error {
+	return nil
+}
+
+func (e errDB) Begin(ctx context.Context) (Tx, error) {
+	return nil, e.err
+}
+
+func (e errDB) Query(ctx context.Context, expression superbasic.Expression) (scan.Rows, error) {
+	return nil, e.err
+}
+
+func (e errDB) QueryRow(ctx context.Context, expression superbasic.Expression) scan.Row {
+	return RowError{Err: e.err}
+}
+
+func (e errDB) Exec(ctx context.Context, expression superbasic.Expression) error {
+	return e.err
+}
@@ -0,0 +1,80 @@
+package esperanto_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+// failingClose is a handle that fails to close.
+type failingClose struct {
+	esperanto.DB
+	err error
+}
+
+func (f failingClose) Close() error {
+	_ = f.DB.Close()
+
+	return f.err
+}
+
+func TestMultiDB(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	postgres, sqlserver := openSqlite(t), openSqlite(t)
+
+	multi := esperanto.MultiDB{Handles: map[esperanto.Dialect]esperanto.DB{
+		esperanto.Postgres:  postgres,
+		esperanto.SQLServer: sqlserver,
+	}}
+
+	// each handle gets a table named after the dialect it is routed by
+	for _, dialect := range []esperanto.Dialect{esperanto.Postgres, esperanto.SQLServer} {
+		err := multi.Exec(ctx, dialect, func(dialect esperanto.Dialect) superbasic.Expression {
+			return superbasic.SQL("CREATE TABLE " + string(dialect) + " (id INTEGER)")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for dialect, want := range map[esperanto.Dialect][]int64{esperanto.Postgres: {1, 0}, esperanto.SQLServer: {0, 1}} {
+		got := queryInts(t, multi.For(dialect), "SELECT COUNT(*) FROM sqlite_master WHERE name = 'postgres' "+
+			"UNION ALL SELECT COUNT(*) FROM sqlite_master WHERE name = 'sqlserver'")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", dialect, got, want)
+		}
+	}
+
+	if _, err := multi.For(esperanto.MySQL).Query(ctx, superbasic.SQL("SELECT 1")); !errors.As(err,
+		&esperanto.NoHandleError{}) {
+		t.Errorf("got %v, want a NoHandleError", err)
+	}
+
+	err := multi.Exec(ctx, esperanto.MySQL, func(esperanto.Dialect) superbasic.Expression {
+		return superbasic.SQL("SELECT 1")
+	})
+	if !errors.As(err, &esperanto.NoHandleError{}) {
+		t.Errorf("got %v, want a NoHandleError", err)
+	}
+
+	first, second := errors.New("first"), errors.New("second")
+
+	multi.Handles[esperanto.Postgres] = failingClose{DB: postgres, err: first}
+	multi.Handles[esperanto.SQLServer] = failingClose{DB: sqlserver, err: second}
+
+	var closeErr esperanto.CloseError
+	if err = multi.Close(); !errors.As(err, &closeErr) || !reflect.DeepEqual(closeErr.Errors,
+		map[esperanto.Dialect]error{esperanto.Postgres: first, esperanto.SQLServer: second}) {
+		t.Errorf("got %v, want the errors of both handles", err)
+	}
+
+	if want := "wroge/esperanto error: closing handles: postgres: first; sqlserver: second"; err.Error() != want {
+		t.Errorf("got %s, want %s", err, want)
+	}
+}
@@ -0,0 +1,52 @@
+package esperanto
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ToNamed rewrites the positional placeholders ? or $n of a finalized statement into named
+// placeholders :p0, :p1, ... and maps the names to args. An empty prefix defaults to p.
+// Placeholders in string literals, quoted identifiers and comments are left untouched.
+func ToNamed(sql string, args []any, prefix string) (namedSQL string, namedArgs map[string]any) {
+	if prefix == "" {
+		prefix = "p"
+	}
+
+	builder := &strings.Builder{}
+	namedArgs = make(map[string]any, len(args))
+	position := 0
+
+	name := func(index int) {
+		key := prefix + strconv.Itoa(index)
+
+		if index < len(args) {
+			namedArgs[key] = args[index]
+		}
+
+		builder.WriteString(":" + key)
+	}
+
+	for _, t := range lex(sql) {
+		switch {
+		case t.kind == tokenPlaceholder:
+			name(position)
+			position++
+		case t.kind == tokenEscaped:
+			builder.WriteString("?")
+		case t.kind == tokenWord && len(t.text) > 1 && t.text[0] == '$':
+			n, err := strconv.Atoi(t.text[1:])
+			if err != nil || n < 1 {
+				builder.WriteString(t.text)
+
+				continue
+			}
+
+			name(n - 1)
+		default:
+			builder.WriteString(t.text)
+		}
+	}
+
+	return builder.String(), namedArgs
+}
@@ -0,0 +1,101 @@
+package esperanto_test
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+// fromNamed rewrites the named placeholders of ToNamed back into positional placeholders of the form
+// ? or $n and collects the arguments in order. It doesn't skip string literals, so the test queries don't
+// contain names in literals.
+func fromNamed(sql string, args map[string]any, prefix string, dollar bool) (string, []any) {
+	var positional []any
+
+	sql = regexp.MustCompile(`:`+prefix+`(\d+)`).ReplaceAllStringFunc(sql, func(name string) string {
+		index, _ := strconv.Atoi(name[len(prefix)+1:])
+
+		if dollar {
+			for len(positional) <= index {
+				positional = append(positional, nil)
+			}
+
+			positional[index] = args[name[1:]]
+
+			return fmt.Sprintf("$%d", index+1)
+		}
+
+		positional = append(positional, args[name[1:]])
+
+		return "?"
+	})
+
+	return sql, positional
+}
+
+func TestToNamed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		placeholder string
+		expr        superbasic.Expression
+		named       string
+		dollar      bool
+	}{
+		{
+			placeholder: "?",
+			expr:        superbasic.SQL("SELECT * FROM t WHERE a = ? AND b = 'x??' AND c = ? -- d = ??", 1, "c"),
+			named:       "SELECT * FROM t WHERE a = :p0 AND b = 'x?' AND c = :p1 -- d = ?",
+		},
+		{
+			placeholder: "$%d",
+			expr:        superbasic.SQL("SELECT * FROM t WHERE a = ? AND \"$1\" = ?", 1, 2),
+			named:       "SELECT * FROM t WHERE a = :p0 AND \"$1\" = :p1",
+			dollar:      true,
+		},
+	}
+
+	for _, test := range tests {
+		sql, args, err := esperanto.Finalize(esperanto.Placeholders(test.placeholder), test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		named, namedArgs := esperanto.ToNamed(sql, args, "")
+		if named != test.named {
+			t.Errorf("got %s, want %s", named, test.named)
+		}
+
+		positional, positionalArgs := fromNamed(named, namedArgs, "p", test.dollar)
+		if positional != sql || !reflect.DeepEqual(positionalArgs, args) {
+			t.Errorf("got %s %v, want %s %v", positional, positionalArgs, sql, args)
+		}
+	}
+}
+
+func TestToNamedRepeated(t *testing.T) {
+	t.Parallel()
+
+	// a $n used twice refers to the same argument and gets the same name
+	sql := "SELECT * FROM t WHERE a = $1 OR b = $1 OR c = $2"
+	args := []any{1, 2}
+
+	named, namedArgs := esperanto.ToNamed(sql, args, "arg")
+	if want := "SELECT * FROM t WHERE a = :arg0 OR b = :arg0 OR c = :arg1"; named != want {
+		t.Errorf("got %s, want %s", named, want)
+	}
+
+	if want := map[string]any{"arg0": 1, "arg1": 2}; !reflect.DeepEqual(namedArgs, want) {
+		t.Errorf("got %v, want %v", namedArgs, want)
+	}
+
+	positional, positionalArgs := fromNamed(named, namedArgs, "arg", true)
+	if positional != sql || !reflect.DeepEqual(positionalArgs, args) {
+		t.Errorf("got %s %v, want %s %v", positional, positionalArgs, sql, args)
+	}
+}
@@ -0,0 +1,360 @@
+//nolint:wrapcheck
+package esperanto
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+// rowQuerier is implemented by DB and Tx.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, expression superbasic.Expression) scan.Row
+}
+
+// count runs a query returning a single number.
+func count(ctx context.Context, db rowQuerier, expression superbasic.Expression) (int64, error) {
+	return scan.One[int64](db.QueryRow(ctx, expression), scan.Any(func(n *int64, value int64) { *n = value }))
+}
+
+// ColumnExists reports whether table in the current schema has column.
+// Oracle compares uppercased names.
+func ColumnExists(ctx context.Context, db DB, dialect Dialect, table, column string) (bool, error) {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres:
+		expression = superbasic.SQL("SELECT COUNT(*) FROM information_schema.columns "+
+			"WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?", table, column)
+	case MySQL:
+		expression = superbasic.SQL("SELECT COUNT(*) FROM information_schema.columns "+
+			"WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, column)
+	case Sqlite:
+		expression = superbasic.SQL("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column)
+	case SQLServer:
+		expression = superbasic.SQL("SELECT COUNT(*) FROM sys.columns WHERE object_id = OBJECT_ID(?) AND name = ?",
+			table, column)
+	case Oracle:
+		expression = superbasic.SQL("SELECT COUNT(*) FROM user_tab_columns "+
+			"WHERE table_name = UPPER(?) AND column_name = UPPER(?)", table, column)
+	default:
+		expression = missingDialect(dialect, "ColumnExists")
+	}
+
+	n, err := count(ctx, db, expression)
+
+	return n > 0, err
+}
+
+// EstimateCount returns the approximate number of rows of table from the statistics of the dialect.
+// The estimate is only as fresh as the last analyze of the table. Sqlite has no statistics and counts exactly.
+func EstimateCount(ctx context.Context, db DB, dialect Dialect, table string) (int64, error) {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres:
+		expression = superbasic.SQL("SELECT CAST(GREATEST(reltuples, 0) AS BIGINT) FROM pg_class "+
+			"WHERE oid = CAST(? AS regclass)", table)
+	case MySQL:
+		expression = superbasic.SQL("SELECT COALESCE(table_rows, 0) FROM information_schema.tables "+
+			"WHERE table_schema = DATABASE() AND table_name = ?", table)
+	case Sqlite:
+		expression = superbasic.Compile("SELECT COUNT(*) FROM ?", Ident(dialect, table))
+	case SQLServer:
+		expression = superbasic.SQL("SELECT COALESCE(SUM(row_count), 0) FROM sys.dm_db_partition_stats "+
+			"WHERE object_id = OBJECT_ID(?) AND index_id IN (0, 1)", table)
+	case Oracle:
+		expression = superbasic.SQL("SELECT NVL(num_rows, 0) FROM user_tables WHERE table_name = UPPER(?)", table)
+	default:
+		expression = missingDialect(dialect, "EstimateCount")
+	}
+
+	return count(ctx, db, expression)
+}
+
+// currentSchema binds schema or falls back to the current schema of the dialect.
+func currentSchema(dialect Dialect, schema string) superbasic.Expression {
+	if schema != "" {
+		if dialect == Oracle {
+			return superbasic.SQL("UPPER(?)", schema)
+		}
+
+		return superbasic.Value(schema)
+	}
+
+	switch dialect {
+	case Postgres:
+		return superbasic.SQL("current_schema()")
+	case MySQL:
+		return superbasic.SQL("DATABASE()")
+	case SQLServer:
+		return superbasic.SQL("SCHEMA_NAME()")
+	case Oracle:
+		return superbasic.SQL("USER")
+	default:
+		return missingDialect(dialect, "current schema")
+	}
+}
+
+// TableExists reports whether schema has table. An empty schema defaults to the current schema,
+// in Sqlite schema is the name of an attached database. Oracle compares uppercased names.
+func TableExists(ctx context.Context, db DB, dialect Dialect, schema, table string) (bool, error) {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres, MySQL, SQLServer:
+		expression = superbasic.Compile("SELECT COUNT(*) FROM information_schema.tables "+
+			"WHERE table_schema = ? AND table_name = ?", currentSchema(dialect, schema), superbasic.Value(table))
+	case Sqlite:
+		master := "sqlite_master"
+		if schema != "" {
+			master = schema + ".sqlite_master"
+		}
+
+		expression = superbasic.SQL("SELECT COUNT(*) FROM "+master+" WHERE type = 'table' AND name = ?", table)
+	case Oracle:
+		expression = superbasic.Compile("SELECT COUNT(*) FROM all_tables WHERE owner = ? AND table_name = UPPER(?)",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	default:
+		expression = missingDialect(dialect, "TableExists")
+	}
+
+	n, err := count(ctx, db, expression)
+
+	return n > 0, err
+}
+
+// pragma calls a table-valued pragma function of Sqlite on table, optionally in an attached schema.
+func pragma(name, schema, table string) superbasic.Expression {
+	if schema != "" {
+		return superbasic.SQL(fmt.Sprintf("pragma_%s(?, ?)", name), table, schema)
+	}
+
+	return superbasic.SQL(fmt.Sprintf("pragma_%s(?)", name), table)
+}
+
+// ColumnInfo describes a column of a table. Type is the lowercased type name of the dialect
+// and Default is the default expression as text or empty.
+type ColumnInfo struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  string
+}
+
+// TableColumns returns the columns of table in their order. An empty schema defaults to the current schema.
+func TableColumns(ctx context.Context, db DB, dialect Dialect, schema, table string) ([]ColumnInfo, error) {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres, MySQL, SQLServer:
+		expression = superbasic.Compile("SELECT column_name, data_type, is_nullable, column_default "+
+			"FROM information_schema.columns WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	case Sqlite:
+		expression = superbasic.Compile(`SELECT name, type, CASE WHEN "notnull" = 0 THEN 'YES' ELSE 'NO' END, dflt_value `+
+			"FROM ? ORDER BY cid", pragma("table_info", schema, table))
+	case Oracle:
+		expression = superbasic.Compile("SELECT column_name, data_type, "+
+			"CASE nullable WHEN 'Y' THEN 'YES' ELSE 'NO' END, data_default "+
+			"FROM all_tab_columns WHERE owner = ? AND table_name = UPPER(?) ORDER BY column_id",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	default:
+		expression = missingDialect(dialect, "TableColumns")
+	}
+
+	rows, err := db.Query(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return scan.All[ColumnInfo](rows,
+		scan.Any(func(info *ColumnInfo, name string) { info.Name = name }),
+		scan.Any(func(info *ColumnInfo, typ string) { info.Type = strings.ToLower(typ) }),
+		scan.Any(func(info *ColumnInfo, nullable string) { info.Nullable = nullable == "YES" }),
+		scan.Null("", func(info *ColumnInfo, def string) { info.Default = def }),
+	)
+}
+
+// PrimaryKeyColumns returns the primary key columns of table in key order. An empty schema defaults
+// to the current schema.
+func PrimaryKeyColumns(ctx context.Context, db DB, dialect Dialect, schema, table string) ([]string, error) {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres, MySQL, SQLServer:
+		expression = superbasic.Compile("SELECT kcu.column_name FROM information_schema.table_constraints tc "+
+			"JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name "+
+			"AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name "+
+			"WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = ? AND tc.table_name = ? "+
+			"ORDER BY kcu.ordinal_position", currentSchema(dialect, schema), superbasic.Value(table))
+	case Sqlite:
+		expression = superbasic.Compile("SELECT name FROM ? WHERE pk > 0 ORDER BY pk", pragma("table_info", schema, table))
+	case Oracle:
+		expression = superbasic.Compile("SELECT cc.column_name FROM all_constraints c "+
+			"JOIN all_cons_columns cc ON cc.owner = c.owner AND cc.constraint_name = c.constraint_name "+
+			"WHERE c.constraint_type = 'P' AND c.owner = ? AND c.table_name = UPPER(?) ORDER BY cc.position",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	default:
+		expression = missingDialect(dialect, "PrimaryKeyColumns")
+	}
+
+	rows, err := db.Query(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return scan.All[string](rows, scan.Any(func(name *string, value string) { *name = value }))
+}
+
+// FKInfo describes a foreign key of a table. Sqlite has no constraint names and uses the id of the foreign key,
+// an empty referenced column refers to the primary key of RefTable. Oracle has no update rules.
+type FKInfo struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnDelete   RefAction
+	OnUpdate   RefAction
+}
+
+// ForeignKeys returns the foreign keys of table ordered by name. An empty schema defaults to the current schema.
+func ForeignKeys(ctx context.Context, db DB, dialect Dialect, schema, table string) ([]FKInfo, error) {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres:
+		expression = superbasic.Compile("SELECT kcu.constraint_name, kcu.column_name, ref.table_name, ref.column_name, "+
+			"rc.delete_rule, rc.update_rule FROM information_schema.referential_constraints rc "+
+			"JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema "+
+			"AND kcu.constraint_name = rc.constraint_name "+
+			"JOIN information_schema.key_column_usage ref ON ref.constraint_schema = rc.unique_constraint_schema "+
+			"AND ref.constraint_name = rc.unique_constraint_name AND ref.ordinal_position = kcu.position_in_unique_constraint "+
+			"WHERE kcu.table_schema = ? AND kcu.table_name = ? ORDER BY kcu.constraint_name, kcu.ordinal_position",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	case MySQL:
+		expression = superbasic.Compile("SELECT kcu.constraint_name, kcu.column_name, kcu.referenced_table_name, "+
+			"kcu.referenced_column_name, rc.delete_rule, rc.update_rule FROM information_schema.key_column_usage kcu "+
+			"JOIN information_schema.referential_constraints rc ON rc.constraint_schema = kcu.constraint_schema "+
+			"AND rc.constraint_name = kcu.constraint_name AND rc.table_name = kcu.table_name "+
+			"WHERE kcu.table_schema = ? AND kcu.table_name = ? ORDER BY kcu.constraint_name, kcu.ordinal_position",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	case Sqlite:
+		expression = superbasic.Compile(`SELECT CAST(id AS TEXT), "from", "table", "to", on_delete, on_update `+
+			"FROM ? ORDER BY id, seq", pragma("foreign_key_list", schema, table))
+	case SQLServer:
+		name := table
+		if schema != "" {
+			name = schema + "." + table
+		}
+
+		expression = superbasic.SQL("SELECT fk.name, pc.name, OBJECT_NAME(fk.referenced_object_id), rc.name, "+
+			"fk.delete_referential_action_desc, fk.update_referential_action_desc FROM sys.foreign_keys fk "+
+			"JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id "+
+			"JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id "+
+			"JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id "+
+			"WHERE fk.parent_object_id = OBJECT_ID(?) ORDER BY fk.name, fkc.constraint_column_id", name)
+	case Oracle:
+		expression = superbasic.Compile("SELECT c.constraint_name, cc.column_name, r.table_name, rc.column_name, "+
+			"c.delete_rule, 'NO ACTION' FROM all_constraints c "+
+			"JOIN all_cons_columns cc ON cc.owner = c.owner AND cc.constraint_name = c.constraint_name "+
+			"JOIN all_constraints r ON r.owner = c.r_owner AND r.constraint_name = c.r_constraint_name "+
+			"JOIN all_cons_columns rc ON rc.owner = r.owner AND rc.constraint_name = r.constraint_name "+
+			"AND rc.position = cc.position "+
+			"WHERE c.constraint_type = 'R' AND c.owner = ? AND c.table_name = UPPER(?) ORDER BY c.constraint_name, cc.position",
+			currentSchema(dialect, schema), superbasic.Value(table))
+	default:
+		expression = missingDialect(dialect, "ForeignKeys")
+	}
+
+	rows, err := db.Query(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	action := func(rule string) RefAction {
+		return RefAction(strings.ToUpper(strings.ReplaceAll(rule, "_", " ")))
+	}
+
+	// Each row is a single column pair of a foreign key.
+	pairs, err := scan.All[FKInfo](rows,
+		scan.Any(func(info *FKInfo, name string) { info.Name = name }),
+		scan.Any(func(info *FKInfo, column string) { info.Columns = []string{column} }),
+		scan.Any(func(info *FKInfo, refTable string) { info.RefTable = refTable }),
+		scan.Null("", func(info *FKInfo, refColumn string) { info.RefColumns = []string{refColumn} }),
+		scan.Any(func(info *FKInfo, rule string) { info.OnDelete = action(rule) }),
+		scan.Any(func(info *FKInfo, rule string) { info.OnUpdate = action(rule) }),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []FKInfo
+
+	for _, pair := range pairs {
+		if last := len(keys) - 1; last >= 0 && keys[last].Name == pair.Name {
+			keys[last].Columns = append(keys[last].Columns, pair.Columns...)
+			keys[last].RefColumns = append(keys[last].RefColumns, pair.RefColumns...)
+
+			continue
+		}
+
+		keys = append(keys, pair)
+	}
+
+	return keys, nil
+}
+
+// QueryColumns returns the names of the result columns of query without fetching rows.
+// The rows must report their columns like *sql.Rows.
+func QueryColumns(ctx context.Context, db DB, dialect Dialect, query superbasic.Expression) ([]string, error) {
+	rows, err := db.Query(ctx, MetadataOnly(dialect, query))
+	if err != nil {
+		return nil, err
+	}
+
+	columnRows, ok := rows.(interface{ Columns() ([]string, error) })
+	if !ok {
+		_ = closeRows(rows)
+
+		return nil, ErrNoColumns
+	}
+
+	columns, err := columnRows.Columns()
+	if closeErr := closeRows(rows); err == nil {
+		err = closeErr
+	}
+
+	return columns, err
+}
+
+// QueryColumnTypes returns the types of the result columns of query without fetching rows.
+// The rows must report their column types like *sql.Rows.
+func QueryColumnTypes(ctx context.Context, db DB, dialect Dialect,
+	query superbasic.Expression,
+) ([]*sql.ColumnType, error) {
+	rows, err := db.Query(ctx, MetadataOnly(dialect, query))
+	if err != nil {
+		return nil, err
+	}
+
+	typeRows, ok := rows.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		_ = closeRows(rows)
+
+		return nil, ErrNoColumns
+	}
+
+	types, err := typeRows.ColumnTypes()
+	if closeErr := closeRows(rows); err == nil {
+		err = closeErr
+	}
+
+	return types, err
+}
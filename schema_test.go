@@ -0,0 +1,117 @@
+package esperanto_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+func TestEstimateCount(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t,
+		`CREATE TABLE "order" (id INTEGER)`,
+		`INSERT INTO "order" (id) VALUES (1), (2)`)
+
+	// the table name is quoted, so that keywords can be counted
+	count, err := esperanto.EstimateCount(context.Background(), db, esperanto.Sqlite, "order")
+	if err != nil || count != 2 {
+		t.Errorf("got %d %v, want 2", count, err)
+	}
+}
+
+func TestColumnExists(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t, "CREATE TABLE t (id INTEGER, name TEXT)")
+
+	for column, want := range map[string]bool{"name": true, "missing": false} {
+		exists, err := esperanto.ColumnExists(ctx, db, esperanto.Sqlite, "t", column)
+		if err != nil || exists != want {
+			t.Errorf("%s: got %t %v, want %t", column, exists, err, want)
+		}
+	}
+}
+
+func TestTableExists(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t, "CREATE TABLE t (id INTEGER)")
+
+	for _, test := range []struct {
+		schema, table string
+		want          bool
+	}{
+		{table: "t", want: true},
+		{schema: "main", table: "t", want: true},
+		{table: "missing", want: false},
+	} {
+		exists, err := esperanto.TableExists(ctx, db, esperanto.Sqlite, test.schema, test.table)
+		if err != nil || exists != test.want {
+			t.Errorf("%+v: got %t %v", test, exists, err)
+		}
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t, "CREATE TABLE t (id INTEGER NOT NULL, name TEXT DEFAULT 'x')")
+
+	columns, err := esperanto.TableColumns(context.Background(), db, esperanto.Sqlite, "", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []esperanto.ColumnInfo{
+		{Name: "id", Type: "integer", Nullable: false},
+		{Name: "name", Type: "text", Nullable: true, Default: "'x'"},
+	}
+
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("got %+v, want %+v", columns, want)
+	}
+}
+
+func TestQueryColumns(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER, name TEXT)",
+		"INSERT INTO t (id, name) VALUES (1, 'a')")
+
+	columns, err := esperanto.QueryColumns(context.Background(), db, esperanto.Sqlite,
+		superbasic.SQL("SELECT name, id AS key FROM t"))
+	if err != nil || !reflect.DeepEqual(columns, []string{"name", "key"}) {
+		t.Errorf("got %v %v, want [name key]", columns, err)
+	}
+}
+
+func TestQueryColumnTypes(t *testing.T) {
+	t.Parallel()
+
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER, name TEXT)",
+		"INSERT INTO t (id, name) VALUES (1, 'a')")
+
+	types, err := esperanto.QueryColumnTypes(context.Background(), db, esperanto.Sqlite,
+		superbasic.SQL("SELECT id, name FROM t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(types))
+
+	for i, columnType := range types {
+		names[i] = columnType.Name() + " " + columnType.DatabaseTypeName()
+	}
+
+	if want := []string{"id INTEGER", "name TEXT"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
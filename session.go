@@ -0,0 +1,249 @@
+//nolint:wrapcheck
+package esperanto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/wroge/superbasic"
+)
+
+// Session runs statements on a single connection, like a Tx or a *StdConn.
+type Session interface {
+	Exec(ctx context.Context, expression superbasic.Expression) error
+}
+
+// sessionState sets state that lasts for the connection. It needs a *StdConn, which resets the state on Close,
+// so that it isn't left on a pooled connection.
+func sessionState(ctx context.Context, session Session, name string, set, reset superbasic.Expression) error {
+	conn, ok := session.(*StdConn)
+	if !ok {
+		return fmt.Errorf("wroge/esperanto error: %s needs a *StdConn, since it lasts for the connection", name)
+	}
+
+	if err := conn.Exec(ctx, set); err != nil {
+		return err
+	}
+
+	conn.onClose(reset)
+
+	return nil
+}
+
+// WithStatementTimeout bounds the following statements of session on the server. d must be positive,
+// since a timeout of zero disables the limit on every dialect.
+// Postgres sets a local statement_timeout that ends with the transaction, so session must be a Tx.
+// MySQL sets max_execution_time, which only applies to SELECT statements and lasts for the connection,
+// so session must be a *StdConn that resets it on Close. Use Hint with MAX_EXECUTION_TIME(n) for a single query.
+// SQLServer has no statement timeout. It sets LOCK_TIMEOUT, which only bounds waiting for locks and not the
+// execution of a statement, and lasts for the connection like on MySQL.
+// Oracle and Sqlite return a MissingDialectError.
+func WithStatementTimeout(ctx context.Context, session Session, dialect Dialect, d time.Duration) error {
+	milliseconds := d.Milliseconds()
+	if milliseconds <= 0 {
+		return fmt.Errorf("wroge/esperanto error: WithStatementTimeout of %s, it must be at least a millisecond", d)
+	}
+
+	switch dialect {
+	case Postgres:
+		if _, ok := session.(Tx); !ok {
+			return fmt.Errorf("wroge/esperanto error: WithStatementTimeout on Postgres needs a Tx, " +
+				"since SET LOCAL has no effect outside of a transaction")
+		}
+
+		return session.Exec(ctx, superbasic.SQL(fmt.Sprintf("SET LOCAL statement_timeout = %d", milliseconds)))
+	case MySQL:
+		return sessionState(ctx, session, "WithStatementTimeout on MySQL",
+			superbasic.SQL(fmt.Sprintf("SET SESSION max_execution_time = %d", milliseconds)),
+			superbasic.SQL("SET SESSION max_execution_time = DEFAULT"))
+	case SQLServer:
+		return sessionState(ctx, session, "WithStatementTimeout on SQLServer",
+			superbasic.SQL(fmt.Sprintf("SET LOCK_TIMEOUT %d", milliseconds)),
+			superbasic.SQL("SET LOCK_TIMEOUT -1"))
+	default:
+		return MissingDialectError{Dialect: dialect, Name: "WithStatementTimeout"}
+	}
+}
+
+// ErrLockNotAcquired is returned by AcquireAdvisoryLock if the lock is not acquired within the timeout.
+var ErrLockNotAcquired = errors.New("wroge/esperanto error: advisory lock not acquired")
+
+// AcquireAdvisoryLock acquires an exclusive application lock named key. The lock is held by a transaction
+// that ends by calling release. A timeout of zero or less waits without limit.
+// Postgres hashes key into a transaction-level advisory lock and fails with the lock_timeout error of the server.
+// MySQL holds GET_LOCK on a connection pinned by db.Conn, like StdDB.Conn, since the lock lasts for the session.
+// SQLServer uses sp_getapplock. Oracle and Sqlite return a MissingDialectError.
+func AcquireAdvisoryLock(ctx context.Context, db DB, dialect Dialect, key string,
+	timeout time.Duration,
+) (release func() error, err error) {
+	switch dialect {
+	case Postgres, SQLServer:
+	case MySQL:
+		return acquireMySQLLock(ctx, db, key, timeout)
+	default:
+		return nil, MissingDialectError{Dialect: dialect, Name: "AcquireAdvisoryLock"}
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired int64
+
+	switch dialect {
+	case Postgres:
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(key))
+
+		milliseconds := int64(0)
+		if timeout > 0 {
+			milliseconds = timeout.Milliseconds()
+		}
+
+		err = tx.Exec(ctx, superbasic.SQL(fmt.Sprintf("SET LOCAL lock_timeout = %d", milliseconds)))
+		if err == nil {
+			err = tx.Exec(ctx, superbasic.SQL("SELECT pg_advisory_xact_lock(?)", int64(hash.Sum64())))
+			acquired = 1
+		}
+	case SQLServer:
+		milliseconds := int64(-1)
+		if timeout > 0 {
+			milliseconds = timeout.Milliseconds()
+		}
+
+		var status int64
+
+		status, err = count(ctx, tx, superbasic.SQL("DECLARE @status INT; "+
+			"EXEC @status = sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Transaction', "+
+			"@LockTimeout = ?; SELECT @status", key, milliseconds))
+		if status >= 0 {
+			acquired = 1
+		}
+	}
+
+	if err == nil && acquired != 1 {
+		err = ErrLockNotAcquired
+	}
+
+	if err != nil {
+		return nil, tx.Rollback(ctx, err)
+	}
+
+	return func() error {
+		return tx.Commit(ctx)
+	}, nil
+}
+
+// pinner pins a connection of the pool, e.g. StdDB.
+type pinner interface {
+	Conn(ctx context.Context) (*StdConn, error)
+}
+
+// acquireMySQLLock holds the lock on a pinned connection. Closing the connection releases the lock,
+// even if the context is done.
+func acquireMySQLLock(ctx context.Context, db DB, key string, timeout time.Duration) (func() error, error) {
+	pinner, ok := db.(pinner)
+	if !ok {
+		return nil, fmt.Errorf("wroge/esperanto error: AcquireAdvisoryLock on MySQL needs a DB with Conn like StdDB")
+	}
+
+	conn, err := pinner.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.onClose(superbasic.SQL("DO RELEASE_LOCK(?)", key))
+
+	seconds := -1.0
+	if timeout > 0 {
+		seconds = timeout.Seconds()
+	}
+
+	acquired, err := count(ctx, conn, superbasic.SQL("SELECT COALESCE(GET_LOCK(?, ?), 0)", key, seconds))
+	if err == nil && acquired != 1 {
+		err = ErrLockNotAcquired
+	}
+
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	return conn.Close, nil
+}
+
+// SetTimeZone sets the time zone of the session, e.g. UTC. Postgres sets it for the transaction only,
+// so session must be a Tx. MySQL and Oracle set it for the connection, so session must be a *StdConn
+// that resets it on Close. SQLServer and Sqlite have no session time zone and return a MissingDialectError,
+// in SQLServer convert the expressions with AtTimeZone instead.
+func SetTimeZone(ctx context.Context, session Session, dialect Dialect, tz string) error {
+	var expression superbasic.Expression
+
+	switch dialect {
+	case Postgres:
+		expression = superbasic.SQL("SET LOCAL TIME ZONE " + literal(tz))
+	case MySQL:
+		return sessionState(ctx, session, "SetTimeZone on MySQL",
+			superbasic.SQL("SET time_zone = "+literal(tz)), superbasic.SQL("SET time_zone = DEFAULT"))
+	case Oracle:
+		return sessionState(ctx, session, "SetTimeZone on Oracle",
+			superbasic.SQL("ALTER SESSION SET TIME_ZONE = "+literal(tz)),
+			superbasic.SQL("ALTER SESSION SET TIME_ZONE = LOCAL"))
+	case SQLServer:
+		expression = missingDialect(dialect, "SetTimeZone (use AtTimeZone)")
+	default:
+		expression = missingDialect(dialect, "SetTimeZone")
+	}
+
+	return session.Exec(ctx, expression)
+}
+
+var savepoints atomic.Uint64
+
+// RunInSavepoint runs fn within a savepoint of tx. The savepoint is released if fn succeeds
+// and rolled back if fn fails, so the error of a nested function leaves the transaction usable.
+// SQLServer and Oracle don't release savepoints.
+func RunInSavepoint(ctx context.Context, tx Tx, dialect Dialect, fn func(Tx) error) error {
+	name := fmt.Sprintf("esperanto_%d", savepoints.Add(1))
+
+	var create, release, rollback superbasic.Expression
+
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		create = superbasic.SQL("SAVEPOINT " + name)
+		release = superbasic.SQL("RELEASE SAVEPOINT " + name)
+		rollback = superbasic.SQL("ROLLBACK TO SAVEPOINT " + name)
+	case SQLServer:
+		create = superbasic.SQL("SAVE TRANSACTION " + name)
+		rollback = superbasic.SQL("ROLLBACK TRANSACTION " + name)
+	case Oracle:
+		create = superbasic.SQL("SAVEPOINT " + name)
+		rollback = superbasic.SQL("ROLLBACK TO SAVEPOINT " + name)
+	default:
+		return MissingDialectError{Dialect: dialect, Name: "RunInSavepoint"}
+	}
+
+	if err := tx.Exec(ctx, create); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Exec(ctx, rollback); rollbackErr != nil {
+			return RollbackError{Err: rollbackErr, Wrap: err}
+		}
+
+		return err
+	}
+
+	if release == nil {
+		return nil
+	}
+
+	return tx.Exec(ctx, release)
+}
@@ -0,0 +1,155 @@
+package esperanto_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/superbasic"
+)
+
+// unpinned hides the Conn method of a StdDB.
+type unpinned struct {
+	esperanto.DB
+}
+
+func TestAcquireAdvisoryLock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	_, err := esperanto.AcquireAdvisoryLock(ctx, db, esperanto.Sqlite, "key", time.Second)
+	if !errors.As(err, &esperanto.MissingDialectError{}) {
+		t.Errorf("got %v, want a MissingDialectError", err)
+	}
+
+	// MySQL holds the lock for the session, so it needs a pinned connection
+	if _, err = esperanto.AcquireAdvisoryLock(ctx, unpinned{DB: db}, esperanto.MySQL, "key", time.Second); err == nil {
+		t.Error("got no error for a DB without Conn")
+	}
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx, nil) }()
+
+	err = esperanto.WithStatementTimeout(ctx, tx, esperanto.Sqlite, time.Second)
+	if !errors.As(err, &esperanto.MissingDialectError{}) {
+		t.Errorf("got %v, want a MissingDialectError", err)
+	}
+
+	// max_execution_time and LOCK_TIMEOUT last for the connection, so a transaction would leave them in the pool
+	for _, dialect := range []esperanto.Dialect{esperanto.MySQL, esperanto.SQLServer} {
+		if err = esperanto.WithStatementTimeout(ctx, tx, dialect, time.Second); err == nil {
+			t.Errorf("got no error for a transaction on %s", dialect)
+		}
+	}
+
+	// a timeout of zero disables the limit
+	if err = esperanto.WithStatementTimeout(ctx, tx, esperanto.Postgres, 0); err == nil {
+		t.Error("got no error for a timeout of zero")
+	}
+
+	// the transaction holds the only connection of db
+	conn, err := openSqlite(t).Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	// SET LOCAL has no effect outside of a transaction
+	if err = esperanto.WithStatementTimeout(ctx, conn, esperanto.Postgres, time.Second); err == nil {
+		t.Error("got no error for a connection on Postgres")
+	}
+}
+
+func TestSetTimeZone(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = esperanto.SetTimeZone(ctx, conn, esperanto.SQLServer, "UTC")
+	if !errors.As(err, &esperanto.MissingDialectError{}) {
+		t.Errorf("got %v, want a MissingDialectError", err)
+	}
+
+	if err = conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx, nil) }()
+
+	// the time zone lasts for the connection, so a transaction would leave it in the pool
+	if err = esperanto.SetTimeZone(ctx, tx, esperanto.MySQL, "UTC"); err == nil {
+		t.Error("got no error for a transaction on MySQL")
+	}
+}
+
+func TestRunInSavepoint(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t, "CREATE TABLE t (id INTEGER)")
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(id int) func(esperanto.Tx) error {
+		return func(tx esperanto.Tx) error {
+			return tx.Exec(ctx, superbasic.SQL("INSERT INTO t (id) VALUES (?)", id))
+		}
+	}
+
+	if err = esperanto.RunInSavepoint(ctx, tx, esperanto.Sqlite, insert(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("failure")
+
+	// the insert of the failing function is rolled back, the transaction stays usable
+	err = esperanto.RunInSavepoint(ctx, tx, esperanto.Sqlite, func(tx esperanto.Tx) error {
+		if err := insert(2)(tx); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("got %v, want %v", err, failure)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := queryInts(t, db, "SELECT id FROM t"); !reflect.DeepEqual(ids, []int64{1}) {
+		t.Errorf("got %v, want [1]", ids)
+	}
+}
@@ -0,0 +1,754 @@
+//nolint:ireturn
+package esperanto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wroge/superbasic"
+)
+
+// Assignment is a single column = value pair of a SET clause.
+type Assignment struct {
+	Column string
+	Value  superbasic.Expression
+}
+
+func set(assignments []Assignment) superbasic.Expression {
+	return keywordCompile("SET ?", superbasic.Join(", ", superbasic.Map(assignments,
+		func(_ int, assignment Assignment) superbasic.Expression {
+			return keywordCompile(assignment.Column+" = ?", assignment.Value)
+		})...))
+}
+
+func where(expr superbasic.Expression) superbasic.Expression {
+	if expr == nil {
+		return superbasic.Raw{}
+	}
+
+	return keywordCompile("WHERE ?", expr)
+}
+
+// Direction is the sort direction of an OrderItem.
+type Direction string
+
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+// NullsOrder places NULL values first or last in an order.
+type NullsOrder string
+
+const (
+	NullsFirst NullsOrder = "NULLS FIRST"
+	NullsLast  NullsOrder = "NULLS LAST"
+)
+
+// OrderItem is a column and its sort direction. An empty Nulls keeps the default of the dialect.
+type OrderItem struct {
+	Column    string
+	Direction Direction
+	Nulls     NullsOrder
+}
+
+// OrderBy renders an ORDER BY clause. Without items nothing is rendered.
+// MySQL and SQLServer emulate Nulls with a preceding sort key, that sorts NULL values independent of Direction.
+func OrderBy(dialect Dialect, items ...OrderItem) superbasic.Expression {
+	if len(items) == 0 {
+		return superbasic.Raw{}
+	}
+
+	keys := make([]string, 0, len(items))
+
+	for _, item := range items {
+		key := item.Column
+		if item.Direction != "" {
+			key = fmt.Sprintf("%s %s", item.Column, item.Direction)
+		}
+
+		switch {
+		case item.Nulls == "":
+		case dialect == Postgres || dialect == Oracle || dialect == Sqlite:
+			key = fmt.Sprintf("%s %s", key, item.Nulls)
+		case dialect == MySQL || dialect == SQLServer:
+			first, last := 0, 1
+			if item.Nulls == NullsLast {
+				first, last = last, first
+			}
+
+			keys = append(keys, fmt.Sprintf("CASE WHEN %s IS NULL THEN %d ELSE %d END", item.Column, first, last))
+		default:
+			return missingDialect(dialect, "OrderBy with Nulls")
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keywordSQL("ORDER BY " + strings.Join(keys, ", "))
+}
+
+// UpdateLimited updates at most limit rows of table in the given order.
+// MySQL uses UPDATE ... ORDER BY ... LIMIT, the other dialects select the keyColumn
+// of the affected rows in a subquery, since Sqlite only supports LIMIT in special builds.
+func UpdateLimited(
+	dialect Dialect,
+	table, keyColumn string,
+	assignments []Assignment,
+	filter superbasic.Expression,
+	order []OrderItem,
+	limit int,
+) superbasic.Expression {
+	update := keywordCompile(fmt.Sprintf("UPDATE %s ?", table), set(assignments))
+
+	switch dialect {
+	case MySQL:
+		return superbasic.Join(" ", update, where(filter), OrderBy(dialect, order...), keywordSQL("LIMIT ?", limit))
+	case Postgres, Sqlite:
+		return superbasic.Join(" ", update, keywordCompile(fmt.Sprintf("WHERE %s IN (?)", keyColumn),
+			superbasic.Join(" ", keywordSQL(fmt.Sprintf("SELECT %s FROM %s", keyColumn, table)),
+				where(filter), OrderBy(dialect, order...), keywordSQL("LIMIT ?", limit))))
+	case SQLServer:
+		return superbasic.Join(" ", update, keywordCompile(fmt.Sprintf("WHERE %s IN (?)", keyColumn),
+			superbasic.Join(" ", keywordSQL(fmt.Sprintf("SELECT TOP (?) %s FROM %s", keyColumn, table), limit),
+				where(filter), OrderBy(dialect, order...))))
+	case Oracle:
+		return superbasic.Join(" ", update, keywordCompile(fmt.Sprintf("WHERE %s IN (?)", keyColumn),
+			superbasic.Join(" ", keywordSQL(fmt.Sprintf("SELECT %s FROM %s", keyColumn, table)),
+				where(filter), OrderBy(dialect, order...), keywordSQL("FETCH FIRST ? ROWS ONLY", limit))))
+	default:
+		return missingDialect(dialect, "UpdateLimited")
+	}
+}
+
+// LockOptions control how locked rows are handled by ForUpdate.
+type LockOptions struct {
+	NoWait     bool
+	SkipLocked bool
+}
+
+func lockClause(clause string, opts LockOptions) superbasic.Expression {
+	switch {
+	case opts.NoWait && opts.SkipLocked:
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: NOWAIT and SKIP LOCKED are exclusive in '%s'", clause)}
+	case opts.NoWait:
+		return keywordSQL(clause + " NOWAIT")
+	case opts.SkipLocked:
+		return keywordSQL(clause + " SKIP LOCKED")
+	default:
+		return keywordSQL(clause)
+	}
+}
+
+// ForUpdate renders a FOR UPDATE clause.
+// SQLServer locks rows by table hints like WITH (UPDLOCK) and Sqlite has no row locks,
+// so both return a MissingDialectError.
+func ForUpdate(dialect Dialect, opts LockOptions) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Oracle:
+		return lockClause("FOR UPDATE", opts)
+	default:
+		return missingDialect(dialect, "ForUpdate")
+	}
+}
+
+// ForShare renders a FOR SHARE clause, which locks rows against changes but not against other shared locks.
+// MySQL needs version 8. SQLServer locks rows by table hints like WITH (HOLDLOCK), Oracle has no
+// shared row locks and Sqlite has no row locks, so they return a MissingDialectError.
+func ForShare(dialect Dialect, opts LockOptions) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL:
+		return lockClause("FOR SHARE", opts)
+	default:
+		return missingDialect(dialect, "ForShare")
+	}
+}
+
+// DequeueJobs selects and locks up to limit rows of table and skips rows locked by other transactions.
+// It has to be run in a transaction. Sqlite has no row locks and returns a MissingDialectError.
+// Oracle evaluates ROWNUM before skipping locked rows, so concurrent workers would get no rows at all.
+// There the statement selects all unlocked rows, which are locked as they are fetched, and the caller
+// limits them with WithMaxRows(limit) and WithTruncate.
+func DequeueJobs(dialect Dialect, table string, filter superbasic.Expression, limit int) superbasic.Expression {
+	skipLocked := ForUpdate(dialect, LockOptions{SkipLocked: true})
+
+	switch dialect {
+	case Postgres, MySQL:
+		return superbasic.Join(" ", keywordSQL("SELECT * FROM "+table), where(filter),
+			keywordSQL("LIMIT ?", limit), skipLocked)
+	case Oracle:
+		return superbasic.Join(" ", keywordSQL("SELECT * FROM "+table), where(filter), skipLocked)
+	case SQLServer:
+		return superbasic.Join(" ",
+			keywordSQL(fmt.Sprintf("SELECT TOP (?) * FROM %s WITH (ROWLOCK, READPAST, UPDLOCK)", table), limit),
+			where(filter))
+	default:
+		return missingDialect(dialect, "DequeueJobs")
+	}
+}
+
+// LimitWithTies selects the first n rows of query in the given order including rows tied with the last one.
+// The order columns refer to result columns of query. MySQL and Sqlite count the preceding rows of
+// each row in a correlated subquery, which emulates RANK() without adding a column.
+// The emulation compares NULL values null-safely and sorts them like OrderBy, respecting Nulls.
+func LimitWithTies(dialect Dialect, query superbasic.Expression, n int, order []OrderItem) superbasic.Expression {
+	if len(order) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: LimitWithTies needs an order")}
+	}
+
+	switch dialect {
+	case Postgres:
+		return keywordCompile("SELECT * FROM (?) AS t ? FETCH FIRST ? ROWS WITH TIES",
+			query, OrderBy(dialect, order...), superbasic.Value(n))
+	case Oracle:
+		return keywordCompile("SELECT * FROM (?) t ? FETCH FIRST ? ROWS WITH TIES",
+			query, OrderBy(dialect, order...), superbasic.Value(n))
+	case SQLServer:
+		return keywordCompile("SELECT TOP (?) WITH TIES * FROM (?) AS t ?",
+			superbasic.Value(n), query, OrderBy(dialect, order...))
+	case MySQL, Sqlite:
+		return keywordCompile("SELECT * FROM (?) AS t WHERE (SELECT COUNT(*) FROM (?) AS u WHERE ?) < ? ?",
+			query, query, precedes(dialect, order), superbasic.Value(n), OrderBy(dialect, order...))
+	default:
+		return missingDialect(dialect, "LimitWithTies")
+	}
+}
+
+// precedes renders a predicate that is true if row u comes before row t in the given order.
+// NULL values are equal to each other and sort first, unless the order is descending or Nulls says otherwise.
+func precedes(dialect Dialect, order []OrderItem) superbasic.Expression {
+	equal := "%s IS %s"
+	if dialect == MySQL {
+		equal = "%s <=> %s"
+	}
+
+	ors := make([]string, len(order))
+
+	for i, item := range order {
+		ands := make([]string, 0, i+1)
+
+		for _, previous := range order[:i] {
+			ands = append(ands, fmt.Sprintf(equal, "u."+previous.Column, "t."+previous.Column))
+		}
+
+		operator := "<"
+		if item.Direction == Desc {
+			operator = ">"
+		}
+
+		nullsFirst := item.Nulls == NullsFirst || (item.Nulls == "" && item.Direction != Desc)
+
+		before, after := "u", "t"
+		if !nullsFirst {
+			before, after = after, before
+		}
+
+		ands = append(ands, fmt.Sprintf("(%s.%s IS NULL AND %s.%s IS NOT NULL OR u.%s %s t.%s)",
+			before, item.Column, after, item.Column, item.Column, operator, item.Column))
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+
+	return keywordSQL(strings.Join(ors, " OR "))
+}
+
+// OrderByCI renders an ORDER BY clause that sorts column case-insensitively.
+func OrderByCI(dialect Dialect, column string, dir Direction) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Oracle:
+		return OrderBy(dialect, OrderItem{Column: fmt.Sprintf("LOWER(%s)", column), Direction: dir})
+	case SQLServer:
+		return OrderBy(dialect, OrderItem{Column: column + " COLLATE Latin1_General_CI_AS", Direction: dir})
+	case Sqlite:
+		return OrderBy(dialect, OrderItem{Column: column + " COLLATE NOCASE", Direction: dir})
+	default:
+		return missingDialect(dialect, "OrderByCI")
+	}
+}
+
+// InsertDefaults inserts a row that consists of default values only.
+// Oracle has no such statement and returns a MissingDialectError.
+func InsertDefaults(dialect Dialect, table string) superbasic.Expression {
+	switch dialect {
+	case Postgres, Sqlite, SQLServer:
+		return keywordSQL(fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table))
+	case MySQL:
+		return keywordSQL(fmt.Sprintf("INSERT INTO %s () VALUES ()", table))
+	default:
+		return missingDialect(dialect, "InsertDefaults")
+	}
+}
+
+// BulkUpdate updates the columns of many rows of table in one statement. toRow returns the key
+// followed by one value per column. Postgres and Sqlite join a VALUES list, SQLServer and Oracle merge it.
+// MySQL uses INSERT ... ON DUPLICATE KEY UPDATE and therefore inserts rows with unknown keys.
+// Postgres may need casted values, as parameters in a VALUES list are typed as text.
+func BulkUpdate[MODEL any](
+	dialect Dialect,
+	table, keyColumn string,
+	columns []string,
+	models []MODEL,
+	toRow func(MODEL) []any,
+) superbasic.Expression {
+	if len(models) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: BulkUpdate of '%s' without models", table)}
+	}
+
+	names := strings.Join(append([]string{keyColumn}, columns...), ", ")
+
+	values := superbasic.Join(", ", superbasic.Map(models, func(_ int, model MODEL) superbasic.Expression {
+		return superbasic.Values(toRow(model))
+	})...)
+
+	assign := func(format string) string {
+		return strings.Join(superbasic.Map(columns, func(_ int, column string) string {
+			return fmt.Sprintf(format, column, column)
+		}), ", ")
+	}
+
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("UPDATE %s SET %s FROM (VALUES ?) AS v (%s) WHERE %s.%s = v.%s",
+			table, assign("%s = v.%s"), names, table, keyColumn, keyColumn), values)
+	case Sqlite:
+		return keywordCompile(fmt.Sprintf("WITH v (%s) AS (VALUES ?) UPDATE %s SET %s FROM v WHERE %s.%s = v.%s",
+			names, table, assign("%s = v.%s"), table, keyColumn, keyColumn), values)
+	case MySQL:
+		return keywordCompile(fmt.Sprintf("INSERT INTO %s (%s) VALUES ? ON DUPLICATE KEY UPDATE %s",
+			table, names, assign("%s = VALUES(%s)")), values)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("MERGE INTO %s USING (VALUES ?) AS v (%s) ON %s.%s = v.%s "+
+			"WHEN MATCHED THEN UPDATE SET %s;", table, names, table, keyColumn, keyColumn, assign("%s = v.%s")), values)
+	case Oracle:
+		aliases := strings.Join(superbasic.Map(append([]string{keyColumn}, columns...), func(_ int, name string) string {
+			return "? " + name
+		}), ", ")
+
+		selects := keywordJoin(" UNION ALL ", superbasic.Map(models, func(_ int, model MODEL) superbasic.Expression {
+			return keywordSQL(fmt.Sprintf("SELECT %s FROM dual", aliases), toRow(model)...)
+		})...)
+
+		return keywordCompile(fmt.Sprintf("MERGE INTO %s USING (?) v ON (%s.%s = v.%s) WHEN MATCHED THEN UPDATE SET %s",
+			table, table, keyColumn, keyColumn, assign(table+".%s = v.%s")), selects)
+	default:
+		return missingDialect(dialect, "BulkUpdate")
+	}
+}
+
+// LateralJoin joins a correlated subquery that may refer to columns of the preceding tables.
+// With outer, rows without a match in the subquery are kept. SQLServer and Oracle use APPLY.
+func LateralJoin(dialect Dialect, subquery superbasic.Expression, alias string, outer bool) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL:
+		if outer {
+			return keywordCompile(fmt.Sprintf("LEFT JOIN LATERAL (?) AS %s ON TRUE", alias), subquery)
+		}
+
+		return keywordCompile(fmt.Sprintf("CROSS JOIN LATERAL (?) AS %s", alias), subquery)
+	case SQLServer, Oracle:
+		as := " AS "
+		if dialect == Oracle {
+			as = " "
+		}
+
+		if outer {
+			return keywordCompile("OUTER APPLY (?)"+as+alias, subquery)
+		}
+
+		return keywordCompile("CROSS APPLY (?)"+as+alias, subquery)
+	default:
+		return missingDialect(dialect, "LateralJoin")
+	}
+}
+
+// DeleteReturning deletes the rows of table matching filter and returns columns of the deleted rows.
+// SQLServer renders an OUTPUT clause. MySQL and Oracle return a MissingDialectError.
+func DeleteReturning(dialect Dialect, table string, filter superbasic.Expression, columns []string) superbasic.Expression {
+	if len(columns) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: DeleteReturning of '%s' without columns", table)}
+	}
+
+	switch dialect {
+	case Postgres, Sqlite:
+		return superbasic.Join(" ", keywordSQL("DELETE FROM "+table), where(filter),
+			keywordSQL("RETURNING "+strings.Join(columns, ", ")))
+	case SQLServer:
+		return superbasic.Join(" ", keywordSQL("DELETE FROM "+table),
+			keywordSQL("OUTPUT "+strings.Join(superbasic.Map(columns, func(_ int, column string) string {
+				return "DELETED." + column
+			}), ", ")), where(filter))
+	default:
+		return missingDialect(dialect, "DeleteReturning")
+	}
+}
+
+// BindMode controls whether values of a clause are bound as parameters or inlined as literals.
+type BindMode int
+
+const (
+	// BindDefault binds values where the drivers of the dialect accept parameters.
+	BindDefault BindMode = iota
+	// BindParams always binds values as parameters.
+	BindParams
+	// BindLiterals always inlines values as literals.
+	BindLiterals
+)
+
+// PaginateOptions configures Paginate. By default SQLServer inlines limit and offset,
+// since some versions reject parameters in FETCH NEXT.
+type PaginateOptions struct {
+	Bind BindMode
+}
+
+// Paginate renders a clause that skips offset rows and returns at most limit rows.
+// SQLServer and Oracle use OFFSET ... FETCH NEXT, which in SQLServer requires an ORDER BY clause.
+func Paginate(dialect Dialect, limit, offset int, opts PaginateOptions) superbasic.Expression {
+	if limit < 0 || offset < 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: invalid limit %d or offset %d", limit, offset)}
+	}
+
+	bind := opts.Bind
+	if bind == BindDefault {
+		bind = BindParams
+
+		if dialect == SQLServer {
+			bind = BindLiterals
+		}
+	}
+
+	value := func(n int) superbasic.Expression {
+		if bind == BindLiterals {
+			return keywordSQL(strconv.Itoa(n))
+		}
+
+		return superbasic.Value(n)
+	}
+
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		if offset == 0 {
+			return keywordCompile("LIMIT ?", value(limit))
+		}
+
+		return keywordCompile("LIMIT ? OFFSET ?", value(limit), value(offset))
+	case SQLServer, Oracle:
+		return keywordCompile("OFFSET ? ROWS FETCH NEXT ? ROWS ONLY", value(offset), value(limit))
+	default:
+		return missingDialect(dialect, "Paginate")
+	}
+}
+
+// UpdateFrom updates target with values from the rows of from matching on.
+// Values of the assignments may refer to the columns of from. Oracle updates the columns
+// with a correlated subquery and Sqlite needs version 3.33.
+func UpdateFrom(dialect Dialect, target string, assignments []Assignment, from, on superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, Sqlite:
+		return keywordCompile(fmt.Sprintf("UPDATE %s ? FROM ? WHERE ?", target), set(assignments), from, on)
+	case MySQL:
+		return keywordCompile(fmt.Sprintf("UPDATE %s JOIN ? ON ? ?", target), from, on, set(assignments))
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("UPDATE %s ? FROM %s JOIN ? ON ?", target, target), set(assignments), from, on)
+	case Oracle:
+		columns := strings.Join(superbasic.Map(assignments, func(_ int, assignment Assignment) string {
+			return assignment.Column
+		}), ", ")
+
+		values := superbasic.Join(", ", superbasic.Map(assignments, func(_ int, assignment Assignment) superbasic.Expression {
+			return assignment.Value
+		})...)
+
+		return keywordCompile(fmt.Sprintf("UPDATE %s SET (%s) = (SELECT ? FROM ? WHERE ?) "+
+			"WHERE EXISTS (SELECT 1 FROM ? WHERE ?)", target, columns), values, from, on, from, on)
+	default:
+		return missingDialect(dialect, "UpdateFrom")
+	}
+}
+
+// DeleteUsing deletes the rows of target that have a matching row in using.
+// Oracle and Sqlite use a correlated EXISTS subquery.
+func DeleteUsing(dialect Dialect, target string, using, on superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("DELETE FROM %s USING ? WHERE ?", target), using, on)
+	case MySQL, SQLServer:
+		return keywordCompile(fmt.Sprintf("DELETE %s FROM %s JOIN ? ON ?", target, target), using, on)
+	case Oracle, Sqlite:
+		return keywordCompile(fmt.Sprintf("DELETE FROM %s WHERE EXISTS (SELECT 1 FROM ? WHERE ?)", target), using, on)
+	default:
+		return missingDialect(dialect, "DeleteUsing")
+	}
+}
+
+// Except returns the distinct rows of a that are not in b. Oracle uses MINUS.
+// MySQL supports EXCEPT since version 8.0.31, older versions emulate it with NOT EXISTS if the
+// result columns are given.
+func Except(dialect Dialect, a, b superbasic.Expression, columns ...string) superbasic.Expression {
+	return setOperation(dialect, "EXCEPT", a, b, columns)
+}
+
+// Intersect returns the distinct rows of a that are also in b.
+// MySQL supports INTERSECT since version 8.0.31, older versions emulate it with EXISTS if the
+// result columns are given.
+func Intersect(dialect Dialect, a, b superbasic.Expression, columns ...string) superbasic.Expression {
+	return setOperation(dialect, "INTERSECT", a, b, columns)
+}
+
+func setOperation(dialect Dialect, operator string, a, b superbasic.Expression, columns []string) superbasic.Expression {
+	switch dialect {
+	case Postgres, Sqlite, SQLServer:
+		return keywordCompile(fmt.Sprintf("? %s ?", operator), a, b)
+	case Oracle:
+		if operator == "EXCEPT" {
+			operator = "MINUS"
+		}
+
+		return keywordCompile(fmt.Sprintf("? %s ?", operator), a, b)
+	case MySQL:
+		if len(columns) == 0 {
+			return keywordCompile(fmt.Sprintf("? %s ?", operator), a, b)
+		}
+
+		exists := "EXISTS"
+		if operator == "EXCEPT" {
+			exists = "NOT EXISTS"
+		}
+
+		equal := strings.Join(superbasic.Map(columns, func(_ int, column string) string {
+			return fmt.Sprintf("a.%s <=> b.%s", column, column)
+		}), " AND ")
+
+		return keywordCompile(fmt.Sprintf("SELECT DISTINCT * FROM (?) AS a WHERE %s (SELECT 1 FROM (?) AS b WHERE %s)",
+			exists, equal), a, b)
+	default:
+		return missingDialect(dialect, operator)
+	}
+}
+
+// Union combines the rows of selects with UNION, or UNION ALL to keep duplicates.
+// A single select is returned as it is.
+func Union(all bool, selects ...superbasic.Expression) superbasic.Expression {
+	if len(selects) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: Union without selects")}
+	}
+
+	if all {
+		return keywordJoin(" UNION ALL ", selects...)
+	}
+
+	return keywordJoin(" UNION ", selects...)
+}
+
+// AggFunc is an aggregate function.
+type AggFunc string
+
+const (
+	AggSum   AggFunc = "SUM"
+	AggCount AggFunc = "COUNT"
+	AggAvg   AggFunc = "AVG"
+	AggMin   AggFunc = "MIN"
+	AggMax   AggFunc = "MAX"
+)
+
+// Pivot turns the categories of pivotColumn into columns, that aggregate value per group of groupBy.
+// The columns are named after the categories. SQLServer and Oracle use PIVOT, which needs the
+// categories inlined as literals, the other dialects use conditional aggregation.
+func Pivot(
+	dialect Dialect,
+	source superbasic.Expression,
+	groupBy []string,
+	value superbasic.Expression,
+	pivotColumn string,
+	categories []any,
+	agg AggFunc,
+) superbasic.Expression {
+	if len(categories) == 0 {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: Pivot without categories")}
+	}
+
+	aliases := make([]string, len(categories))
+
+	for i, category := range categories {
+		alias, ok := quoteIdent(dialect, fmt.Sprint(category))
+		if !ok {
+			return missingDialect(dialect, "Pivot")
+		}
+
+		aliases[i] = alias
+	}
+
+	selectList := strings.Join(append(append([]string{}, groupBy...), pivotColumn), ", ")
+
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		aggregates := make([]superbasic.Expression, len(categories))
+
+		for i, category := range categories {
+			aggregates[i] = keywordCompile(fmt.Sprintf("%s(CASE WHEN %s = ? THEN ? END) AS %s", agg, pivotColumn, aliases[i]),
+				superbasic.Value(category), value)
+		}
+
+		columns := superbasic.Join(", ", append(superbasic.Map(groupBy, func(_ int, column string) superbasic.Expression {
+			return superbasic.SQL(column)
+		}), aggregates...)...)
+
+		if len(groupBy) == 0 {
+			return keywordCompile("SELECT ? FROM (?) AS s", columns, source)
+		}
+
+		return keywordCompile(fmt.Sprintf("SELECT ? FROM (?) AS s GROUP BY %s", strings.Join(groupBy, ", ")), columns, source)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("SELECT * FROM (SELECT %s, ? AS pivot_value FROM (?) AS s) AS s "+
+			"PIVOT (%s(pivot_value) FOR %s IN (%s)) AS p", selectList, agg, pivotColumn, strings.Join(aliases, ", ")),
+			value, source)
+	case Oracle:
+		in := make([]string, len(categories))
+
+		for i, category := range categories {
+			switch category := category.(type) {
+			case string:
+				in[i] = fmt.Sprintf("%s AS %s", literal(category), aliases[i])
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+				in[i] = fmt.Sprintf("%v AS %s", category, aliases[i])
+			default:
+				return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: Pivot category %v can't be inlined", category)}
+			}
+		}
+
+		return keywordCompile(fmt.Sprintf("SELECT * FROM (SELECT %s, ? AS pivot_value FROM (?) s) "+
+			"PIVOT (%s(pivot_value) FOR %s IN (%s))", selectList, agg, pivotColumn, strings.Join(in, ", ")),
+			value, source)
+	default:
+		return missingDialect(dialect, "Pivot")
+	}
+}
+
+// Rollup renders a GROUP BY clause with subtotals for the prefixes of columns and a grand total.
+// MySQL uses WITH ROLLUP.
+func Rollup(dialect Dialect, columns ...string) superbasic.Expression {
+	switch dialect {
+	case Postgres, SQLServer, Oracle:
+		return keywordSQL(fmt.Sprintf("GROUP BY ROLLUP (%s)", strings.Join(columns, ", ")))
+	case MySQL:
+		return keywordSQL(fmt.Sprintf("GROUP BY %s WITH ROLLUP", strings.Join(columns, ", ")))
+	default:
+		return missingDialect(dialect, "Rollup")
+	}
+}
+
+// Cube renders a GROUP BY clause with subtotals for all combinations of columns.
+func Cube(dialect Dialect, columns ...string) superbasic.Expression {
+	switch dialect {
+	case Postgres, SQLServer, Oracle:
+		return keywordSQL(fmt.Sprintf("GROUP BY CUBE (%s)", strings.Join(columns, ", ")))
+	default:
+		return missingDialect(dialect, "Cube")
+	}
+}
+
+// GroupingSets renders a GROUP BY clause that groups by each of sets. An empty set is the grand total.
+func GroupingSets(dialect Dialect, sets ...[]string) superbasic.Expression {
+	switch dialect {
+	case Postgres, SQLServer, Oracle:
+		return keywordSQL(fmt.Sprintf("GROUP BY GROUPING SETS (%s)", strings.Join(superbasic.Map(sets,
+			func(_ int, set []string) string {
+				return "(" + strings.Join(set, ", ") + ")"
+			}), ", ")))
+	default:
+		return missingDialect(dialect, "GroupingSets")
+	}
+}
+
+// UpdateIfVersion updates the row of table with key if its versionColumn still has expectedVersion
+// and increments the version. No row is affected if the row has been changed concurrently.
+func UpdateIfVersion(
+	table string,
+	assignments []Assignment,
+	keyColumn string,
+	key any,
+	versionColumn string,
+	expectedVersion int64,
+) superbasic.Expression {
+	assignments = append(append([]Assignment{}, assignments...),
+		Assignment{Column: versionColumn, Value: superbasic.SQL(versionColumn + " + 1")})
+
+	return keywordCompile(fmt.Sprintf("UPDATE %s ? WHERE %s = ? AND %s = ?", table, keyColumn, versionColumn),
+		set(assignments), superbasic.Value(key), superbasic.Value(expectedVersion))
+}
+
+// Default renders the DEFAULT keyword as a value of an INSERT or an Assignment. It must be compiled
+// into the statement, as superbasic.Values binds all values as parameters.
+// Sqlite has no DEFAULT keyword and returns a MissingDialectError. It is not emulated by omitting the
+// column, since the VALUES are compiled by the caller and not by a builder that knows the columns, and a
+// column can't be omitted from a multi-row INSERT in which only some rows take the default.
+// Leave the column out of the INSERT instead, or use InsertDefaults if every column takes its default.
+func Default(dialect Dialect) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, SQLServer, Oracle:
+		return keywordSQL("DEFAULT")
+	default:
+		return missingDialect(dialect, "Default")
+	}
+}
+
+// MetadataOnly wraps a query so that it returns its columns without rows.
+func MetadataOnly(dialect Dialect, query superbasic.Expression) superbasic.Expression {
+	switch dialect {
+	case Postgres, MySQL, Sqlite:
+		return keywordCompile("SELECT * FROM (?) AS t LIMIT 0", query)
+	case SQLServer:
+		return keywordCompile("SELECT TOP (0) * FROM (?) AS t", query)
+	case Oracle:
+		return keywordCompile("SELECT * FROM (?) t WHERE 1 = 0", query)
+	default:
+		return missingDialect(dialect, "MetadataOnly")
+	}
+}
+
+// Hint adds the optimizer hint of dialect from hints to query. MySQL and Oracle place it as /*+ hint */
+// after the first SELECT, Postgres before the query for pg_hint_plan and SQLServer as OPTION (hint).
+// Dialects without a hint in hints return query unchanged.
+func Hint(dialect Dialect, query superbasic.Expression, hints map[Dialect]string) superbasic.Expression {
+	hint, ok := hints[dialect]
+	if !ok {
+		return query
+	}
+
+	if strings.Contains(hint, "*/") {
+		return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: invalid hint '%s'", hint)}
+	}
+
+	hint = strings.ReplaceAll(hint, "?", "??")
+
+	switch dialect {
+	case Postgres:
+		return keywordCompile(fmt.Sprintf("/*+ %s */ ?", hint), query)
+	case SQLServer:
+		return keywordCompile(fmt.Sprintf("?\nOPTION (%s)", hint), query)
+	case MySQL, Oracle:
+		return deferred(func() superbasic.Expression {
+			if query == nil {
+				return nil
+			}
+
+			sql, args, err := query.ToSQL()
+			if err != nil {
+				return superbasic.Raw{Err: err}
+			}
+
+			tokens := lex(sql)
+
+			for i, t := range tokens {
+				if isKeyword(t, "SELECT") {
+					tokens[i].text += fmt.Sprintf(" /*+ %s */", hint)
+
+					return superbasic.SQL(join(tokens), args...)
+				}
+			}
+
+			return superbasic.Raw{Err: fmt.Errorf("wroge/esperanto error: no SELECT to hint in '%s'", sql)}
+		})
+	default:
+		return missingDialect(dialect, "Hint")
+	}
+}
@@ -0,0 +1,571 @@
+package esperanto_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/wroge/esperanto"
+	"github.com/wroge/scan"
+	"github.com/wroge/superbasic"
+)
+
+func TestUpdateLimited(t *testing.T) {
+	t.Parallel()
+
+	update := func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.UpdateLimited(dialect, "jobs", "id",
+			[]esperanto.Assignment{{Column: "done", Value: superbasic.Value(1)}},
+			superbasic.SQL("done = ?", 0), []esperanto.OrderItem{{Column: "id"}}, 2)
+	}
+
+	testSnapshot(t, update, map[esperanto.Dialect]string{
+		esperanto.MySQL:    "UPDATE jobs SET done = ? WHERE done = ? ORDER BY id LIMIT ?",
+		esperanto.Postgres: "UPDATE jobs SET done = $1 WHERE id IN (SELECT id FROM jobs WHERE done = $2 ORDER BY id LIMIT $3)",
+		esperanto.Sqlite:   "UPDATE jobs SET done = ? WHERE id IN (SELECT id FROM jobs WHERE done = ? ORDER BY id LIMIT ?)",
+		esperanto.SQLServer: "UPDATE jobs SET done = @p1 WHERE id IN " +
+			"(SELECT TOP (@p2) id FROM jobs WHERE done = @p3 ORDER BY id)",
+	})
+
+	db := openSqlite(t,
+		"CREATE TABLE jobs (id INTEGER PRIMARY KEY, done INTEGER NOT NULL)",
+		"INSERT INTO jobs (id, done) VALUES (1, 0), (2, 0), (3, 0)")
+
+	if err := db.Exec(context.Background(), update(esperanto.Sqlite)); err != nil {
+		t.Fatal(err)
+	}
+
+	if done := queryInts(t, db, "SELECT id FROM jobs WHERE done = 1 ORDER BY id"); !reflect.DeepEqual(done, []int64{1, 2}) {
+		t.Errorf("got %v, want [1 2]", done)
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DeleteReturning(dialect, "t", superbasic.SQL("a = ?", 1), []string{"id", "a"})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DELETE FROM t WHERE a = $1 RETURNING id, a",
+		esperanto.Sqlite:    "DELETE FROM t WHERE a = ? RETURNING id, a",
+		esperanto.SQLServer: "DELETE FROM t OUTPUT DELETED.id, DELETED.a WHERE a = @p1",
+		esperanto.MySQL:     fails,
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DeleteReturning(dialect, "t", superbasic.SQL("a = ?", 1), nil)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+	})
+}
+
+func TestLimitWithTies(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.LimitWithTies(dialect, superbasic.SQL("SELECT id, score FROM scores"), 1,
+			[]esperanto.OrderItem{{Column: "score", Direction: esperanto.Desc, Nulls: esperanto.NullsFirst}})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "SELECT * FROM (SELECT id, score FROM scores) AS t ORDER BY score DESC NULLS FIRST " +
+			"FETCH FIRST $1 ROWS WITH TIES",
+		esperanto.Sqlite: "SELECT * FROM (SELECT id, score FROM scores) AS t WHERE (SELECT COUNT(*) FROM " +
+			"(SELECT id, score FROM scores) AS u WHERE ((u.score IS NULL AND t.score IS NOT NULL OR u.score > t.score))) " +
+			"< ? ORDER BY score DESC NULLS FIRST",
+	})
+
+	db := openSqlite(t,
+		"CREATE TABLE scores (id INTEGER, score INTEGER)",
+		"INSERT INTO scores (id, score) VALUES (1, NULL), (2, 10), (3, 10), (4, 20), (5, NULL)")
+
+	for _, test := range []struct {
+		n    int
+		item esperanto.OrderItem
+		want []int64
+	}{
+		{n: 1, item: esperanto.OrderItem{Column: "score"}, want: []int64{1, 5}},
+		{n: 2, item: esperanto.OrderItem{Column: "score", Nulls: esperanto.NullsLast}, want: []int64{2, 3}},
+		{n: 1, item: esperanto.OrderItem{Column: "score", Direction: esperanto.Desc}, want: []int64{4}},
+		{n: 1, item: esperanto.OrderItem{Column: "score", Direction: esperanto.Desc, Nulls: esperanto.NullsFirst},
+			want: []int64{1, 5}},
+	} {
+		query := esperanto.LimitWithTies(esperanto.Sqlite, superbasic.SQL("SELECT id, score FROM scores"),
+			test.n, []esperanto.OrderItem{test.item})
+
+		rows, err := db.Query(context.Background(), superbasic.Compile("SELECT id FROM (?) AS r ORDER BY id", query))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids, err := scan.All[int64](rows, scan.Any(func(i *int64, value int64) { *i = value }))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("%+v: got %v, want %v", test.item, ids, test.want)
+		}
+	}
+}
+
+func TestDequeueJobs(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DequeueJobs(dialect, "jobs", superbasic.SQL("state = ?", "ready"), 10)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT * FROM jobs WHERE state = $1 LIMIT $2 FOR UPDATE SKIP LOCKED",
+		esperanto.MySQL:     "SELECT * FROM jobs WHERE state = ? LIMIT ? FOR UPDATE SKIP LOCKED",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "SELECT TOP (@p1) * FROM jobs WITH (ROWLOCK, READPAST, UPDLOCK) WHERE state = @p2",
+		esperanto.Oracle:    "SELECT * FROM jobs WHERE state = :1 FOR UPDATE SKIP LOCKED",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DequeueJobs(dialect, "jobs", nil, 10)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT * FROM jobs LIMIT $1 FOR UPDATE SKIP LOCKED",
+		esperanto.MySQL:     "SELECT * FROM jobs LIMIT ? FOR UPDATE SKIP LOCKED",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "SELECT TOP (@p1) * FROM jobs WITH (ROWLOCK, READPAST, UPDLOCK)",
+		esperanto.Oracle:    "SELECT * FROM jobs FOR UPDATE SKIP LOCKED",
+	})
+}
+
+func TestOrderByCI(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.OrderByCI(dialect, "name", esperanto.Asc)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ORDER BY LOWER(name) ASC",
+		esperanto.MySQL:     "ORDER BY LOWER(name) ASC",
+		esperanto.Sqlite:    "ORDER BY name COLLATE NOCASE ASC",
+		esperanto.SQLServer: "ORDER BY name COLLATE Latin1_General_CI_AS ASC",
+		esperanto.Oracle:    "ORDER BY LOWER(name) ASC",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.OrderByCI(dialect, "name", esperanto.Desc)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "ORDER BY LOWER(name) DESC",
+		esperanto.MySQL:     "ORDER BY LOWER(name) DESC",
+		esperanto.Sqlite:    "ORDER BY name COLLATE NOCASE DESC",
+		esperanto.SQLServer: "ORDER BY name COLLATE Latin1_General_CI_AS DESC",
+		esperanto.Oracle:    "ORDER BY LOWER(name) DESC",
+	})
+}
+
+func TestBulkUpdate(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BulkUpdate(dialect, "users", "id", []string{"name", "age"}, [][]any{{1, "a", 20}, {2, "b", 30}},
+			func(row []any) []any { return row })
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "UPDATE users SET name = v.name, age = v.age FROM (VALUES ($1, $2, $3), ($4, $5, $6)) AS v " +
+			"(id, name, age) WHERE users.id = v.id",
+		esperanto.MySQL: "INSERT INTO users (id, name, age) VALUES (?, ?, ?), (?, ?, ?) ON DUPLICATE KEY UPDATE name = " +
+			"VALUES(name), age = VALUES(age)",
+		esperanto.Sqlite: "WITH v (id, name, age) AS (VALUES (?, ?, ?), (?, ?, ?)) UPDATE users SET name = v.name, age = " +
+			"v.age FROM v WHERE users.id = v.id",
+		esperanto.SQLServer: "MERGE INTO users USING (VALUES (@p1, @p2, @p3), (@p4, @p5, @p6)) AS v (id, name, age) ON " +
+			"users.id = v.id WHEN MATCHED THEN UPDATE SET name = v.name, age = v.age;",
+		esperanto.Oracle: "MERGE INTO users USING (SELECT :1 id, :2 name, :3 age FROM dual UNION ALL SELECT :4 id, :5 " +
+			"name, :6 age FROM dual) v ON (users.id = v.id) WHEN MATCHED THEN UPDATE SET users.name = " +
+			"v.name, users.age = v.age",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.BulkUpdate(dialect, "users", "id", []string{"name"}, [][]any{}, func(row []any) []any { return row })
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestLateralJoin(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.LateralJoin(dialect,
+			superbasic.SQL("SELECT total FROM orders WHERE orders.user_id = users.id AND total > ?", 10), "o", false)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "CROSS JOIN LATERAL (SELECT total FROM orders WHERE orders.user_id = users.id AND total > $1) " +
+			"AS o",
+		esperanto.MySQL: "CROSS JOIN LATERAL (SELECT total FROM orders WHERE orders.user_id = users.id AND total > ?) " +
+			"AS o",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "CROSS APPLY (SELECT total FROM orders WHERE orders.user_id = users.id AND total > @p1) AS o",
+		esperanto.Oracle:    "CROSS APPLY (SELECT total FROM orders WHERE orders.user_id = users.id AND total > :1) o",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.LateralJoin(dialect,
+			superbasic.SQL("SELECT total FROM orders WHERE orders.user_id = users.id"), "o", true)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "LEFT JOIN LATERAL (SELECT total FROM orders WHERE orders.user_id = users.id) AS o ON TRUE",
+		esperanto.MySQL:     "LEFT JOIN LATERAL (SELECT total FROM orders WHERE orders.user_id = users.id) AS o ON TRUE",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "OUTER APPLY (SELECT total FROM orders WHERE orders.user_id = users.id) AS o",
+		esperanto.Oracle:    "OUTER APPLY (SELECT total FROM orders WHERE orders.user_id = users.id) o",
+	})
+}
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Paginate(dialect, 10, 20, esperanto.PaginateOptions{})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "LIMIT $1 OFFSET $2",
+		esperanto.MySQL:     "LIMIT ? OFFSET ?",
+		esperanto.Sqlite:    "LIMIT ? OFFSET ?",
+		esperanto.SQLServer: "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+		esperanto.Oracle:    "OFFSET :1 ROWS FETCH NEXT :2 ROWS ONLY",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Paginate(dialect, 10, 0, esperanto.PaginateOptions{Bind: esperanto.BindParams})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "LIMIT $1",
+		esperanto.MySQL:     "LIMIT ?",
+		esperanto.Sqlite:    "LIMIT ?",
+		esperanto.SQLServer: "OFFSET @p1 ROWS FETCH NEXT @p2 ROWS ONLY",
+		esperanto.Oracle:    "OFFSET :1 ROWS FETCH NEXT :2 ROWS ONLY",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Paginate(dialect, 10, 20, esperanto.PaginateOptions{Bind: esperanto.BindLiterals})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "LIMIT 10 OFFSET 20",
+		esperanto.MySQL:     "LIMIT 10 OFFSET 20",
+		esperanto.Sqlite:    "LIMIT 10 OFFSET 20",
+		esperanto.SQLServer: "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+		esperanto.Oracle:    "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Paginate(dialect, -1, 0, esperanto.PaginateOptions{})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestDeleteUsing(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.DeleteUsing(dialect, "orders", superbasic.SQL("users"),
+			superbasic.SQL("orders.user_id = users.id AND users.banned = ?", true))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "DELETE FROM orders USING users WHERE orders.user_id = users.id AND users.banned = $1",
+		esperanto.MySQL:    "DELETE orders FROM orders JOIN users ON orders.user_id = users.id AND users.banned = ?",
+		esperanto.Sqlite: "DELETE FROM orders WHERE EXISTS (SELECT 1 FROM users WHERE orders.user_id = users.id AND " +
+			"users.banned = ?)",
+		esperanto.SQLServer: "DELETE orders FROM orders JOIN users ON orders.user_id = users.id AND users.banned = @p1",
+		esperanto.Oracle: "DELETE FROM orders WHERE EXISTS (SELECT 1 FROM users WHERE orders.user_id = users.id AND " +
+			"users.banned = :1)",
+	})
+}
+
+func TestExcept(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Except(dialect,
+			superbasic.SQL("SELECT id FROM a WHERE x = ?", 1), superbasic.SQL("SELECT id FROM b WHERE y = ?", 2))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT id FROM a WHERE x = $1 EXCEPT SELECT id FROM b WHERE y = $2",
+		esperanto.MySQL:     "SELECT id FROM a WHERE x = ? EXCEPT SELECT id FROM b WHERE y = ?",
+		esperanto.Sqlite:    "SELECT id FROM a WHERE x = ? EXCEPT SELECT id FROM b WHERE y = ?",
+		esperanto.SQLServer: "SELECT id FROM a WHERE x = @p1 EXCEPT SELECT id FROM b WHERE y = @p2",
+		esperanto.Oracle:    "SELECT id FROM a WHERE x = :1 MINUS SELECT id FROM b WHERE y = :2",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Except(dialect, superbasic.SQL("SELECT id FROM a"), superbasic.SQL("SELECT id FROM b"), "id")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "SELECT id FROM a EXCEPT SELECT id FROM b",
+		esperanto.MySQL: "SELECT DISTINCT * FROM (SELECT id FROM a) AS a WHERE NOT EXISTS (SELECT 1 FROM (SELECT id " +
+			"FROM b) AS b WHERE a.id <=> b.id)",
+		esperanto.Sqlite:    "SELECT id FROM a EXCEPT SELECT id FROM b",
+		esperanto.SQLServer: "SELECT id FROM a EXCEPT SELECT id FROM b",
+		esperanto.Oracle:    "SELECT id FROM a MINUS SELECT id FROM b",
+	})
+}
+
+func TestIntersect(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Intersect(dialect, superbasic.SQL("SELECT id FROM a"), superbasic.SQL("SELECT id FROM b"))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.MySQL:     "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.Sqlite:    "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.SQLServer: "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.Oracle:    "SELECT id FROM a INTERSECT SELECT id FROM b",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Intersect(dialect, superbasic.SQL("SELECT id FROM a"), superbasic.SQL("SELECT id FROM b"), "id")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.MySQL: "SELECT DISTINCT * FROM (SELECT id FROM a) AS a WHERE EXISTS (SELECT 1 FROM (SELECT id FROM b) " +
+			"AS b WHERE a.id <=> b.id)",
+		esperanto.Sqlite:    "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.SQLServer: "SELECT id FROM a INTERSECT SELECT id FROM b",
+		esperanto.Oracle:    "SELECT id FROM a INTERSECT SELECT id FROM b",
+	})
+}
+
+func TestUnion(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Union(false,
+			superbasic.SQL("SELECT id FROM a WHERE x = ? AND y = ?", 1, 2),
+			superbasic.SQL("SELECT id FROM b WHERE z = ?", 3))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT id FROM a WHERE x = $1 AND y = $2 UNION SELECT id FROM b WHERE z = $3",
+		esperanto.MySQL:     "SELECT id FROM a WHERE x = ? AND y = ? UNION SELECT id FROM b WHERE z = ?",
+		esperanto.Sqlite:    "SELECT id FROM a WHERE x = ? AND y = ? UNION SELECT id FROM b WHERE z = ?",
+		esperanto.SQLServer: "SELECT id FROM a WHERE x = @p1 AND y = @p2 UNION SELECT id FROM b WHERE z = @p3",
+		esperanto.Oracle:    "SELECT id FROM a WHERE x = :1 AND y = :2 UNION SELECT id FROM b WHERE z = :3",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Union(true, superbasic.SQL("SELECT id FROM a"), superbasic.SQL("SELECT id FROM b WHERE z = ?", 3))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT id FROM a UNION ALL SELECT id FROM b WHERE z = $1",
+		esperanto.MySQL:     "SELECT id FROM a UNION ALL SELECT id FROM b WHERE z = ?",
+		esperanto.Sqlite:    "SELECT id FROM a UNION ALL SELECT id FROM b WHERE z = ?",
+		esperanto.SQLServer: "SELECT id FROM a UNION ALL SELECT id FROM b WHERE z = @p1",
+		esperanto.Oracle:    "SELECT id FROM a UNION ALL SELECT id FROM b WHERE z = :1",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Union(true)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+
+	// the arguments of both selects are numbered in order
+	_, args, err := esperanto.Finalize(esperanto.Placeholders("$%d"), esperanto.Union(false,
+		superbasic.SQL("SELECT id FROM a WHERE x = ? AND y = ?", 1, 2),
+		superbasic.SQL("SELECT id FROM b WHERE z = ?", 3)))
+	if err != nil || !reflect.DeepEqual(args, []any{1, 2, 3}) {
+		t.Errorf("got %v %v, want [1 2 3]", args, err)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Pivot(dialect, superbasic.SQL("SELECT region, quarter, amount FROM sales"), []string{"region"},
+			superbasic.SQL("amount"), "quarter", []any{"Q1", "Q2"}, esperanto.AggSum)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres: "SELECT region, SUM(CASE WHEN quarter = $1 THEN amount END) AS \"Q1\", SUM(CASE WHEN quarter = " +
+			"$2 THEN amount END) AS \"Q2\" FROM (SELECT region, quarter, amount FROM sales) AS s GROUP BY " +
+			"region",
+		esperanto.MySQL: "SELECT region, SUM(CASE WHEN quarter = ? THEN amount END) AS `Q1`, SUM(CASE WHEN quarter = ? " +
+			"THEN amount END) AS `Q2` FROM (SELECT region, quarter, amount FROM sales) AS s GROUP BY region",
+		esperanto.Sqlite: "SELECT region, SUM(CASE WHEN quarter = ? THEN amount END) AS \"Q1\", SUM(CASE WHEN quarter = " +
+			"? THEN amount END) AS \"Q2\" FROM (SELECT region, quarter, amount FROM sales) AS s GROUP BY " +
+			"region",
+		esperanto.SQLServer: "SELECT * FROM (SELECT region, quarter, amount AS pivot_value FROM (SELECT region, quarter, " +
+			"amount FROM sales) AS s) AS s PIVOT (SUM(pivot_value) FOR quarter IN ([Q1], [Q2])) AS p",
+		esperanto.Oracle: "SELECT * FROM (SELECT region, quarter, amount AS pivot_value FROM (SELECT region, quarter, " +
+			"amount FROM sales) s) PIVOT (SUM(pivot_value) FOR quarter IN ('Q1' AS \"Q1\", 'Q2' AS \"Q2\"))",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Pivot(dialect, superbasic.SQL("sales"), nil, superbasic.SQL("amount"), "quarter", nil, esperanto.AggSum)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  fails,
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestRollup(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Rollup(dialect, "region", "city")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "GROUP BY ROLLUP (region, city)",
+		esperanto.MySQL:     "GROUP BY region, city WITH ROLLUP",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "GROUP BY ROLLUP (region, city)",
+		esperanto.Oracle:    "GROUP BY ROLLUP (region, city)",
+	})
+}
+
+func TestCube(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Cube(dialect, "region", "city")
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "GROUP BY CUBE (region, city)",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "GROUP BY CUBE (region, city)",
+		esperanto.Oracle:    "GROUP BY CUBE (region, city)",
+	})
+}
+
+func TestGroupingSets(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.GroupingSets(dialect, []string{"region", "city"}, []string{"region"}, nil)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "GROUP BY GROUPING SETS ((region, city), (region), ())",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "GROUP BY GROUPING SETS ((region, city), (region), ())",
+		esperanto.Oracle:    "GROUP BY GROUPING SETS ((region, city), (region), ())",
+	})
+}
+
+func TestUpdateIfVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := openSqlite(t,
+		"CREATE TABLE t (id INTEGER, name TEXT, version INTEGER)",
+		"INSERT INTO t (id, name, version) VALUES (1, 'a', 1)")
+
+	update := func(name string, version int64) superbasic.Expression {
+		return esperanto.UpdateIfVersion("t", []esperanto.Assignment{{Column: "name", Value: superbasic.Value(name)}},
+			"id", 1, "version", version)
+	}
+
+	// the second update expects a stale version and affects no row
+	for _, expression := range []superbasic.Expression{update("b", 1), update("c", 1)} {
+		if err := db.Exec(ctx, expression); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if version := queryInts(t, db, "SELECT version FROM t WHERE name = 'b'"); !reflect.DeepEqual(version, []int64{2}) {
+		t.Errorf("got %v, want [2]", version)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return superbasic.Compile("INSERT INTO t (a, b) VALUES (?, ?)", superbasic.Value(1), esperanto.Default(dialect))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "INSERT INTO t (a, b) VALUES ($1, DEFAULT)",
+		esperanto.MySQL:     "INSERT INTO t (a, b) VALUES (?, DEFAULT)",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "INSERT INTO t (a, b) VALUES (@p1, DEFAULT)",
+		esperanto.Oracle:    "INSERT INTO t (a, b) VALUES (:1, DEFAULT)",
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return superbasic.Compile("UPDATE t SET b = ?", esperanto.Default(dialect))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "UPDATE t SET b = DEFAULT",
+		esperanto.MySQL:     "UPDATE t SET b = DEFAULT",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "UPDATE t SET b = DEFAULT",
+		esperanto.Oracle:    "UPDATE t SET b = DEFAULT",
+	})
+}
+
+func TestMetadataOnly(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.MetadataOnly(dialect, superbasic.SQL("SELECT id, name FROM users WHERE age > ?", 18))
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SELECT * FROM (SELECT id, name FROM users WHERE age > $1) AS t LIMIT 0",
+		esperanto.MySQL:     "SELECT * FROM (SELECT id, name FROM users WHERE age > ?) AS t LIMIT 0",
+		esperanto.Sqlite:    "SELECT * FROM (SELECT id, name FROM users WHERE age > ?) AS t LIMIT 0",
+		esperanto.SQLServer: "SELECT TOP (0) * FROM (SELECT id, name FROM users WHERE age > @p1) AS t",
+		esperanto.Oracle:    "SELECT * FROM (SELECT id, name FROM users WHERE age > :1) t WHERE 1 = 0",
+	})
+}
+
+func TestForShare(t *testing.T) {
+	t.Parallel()
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ForShare(dialect, esperanto.LockOptions{})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FOR SHARE",
+		esperanto.MySQL:     "FOR SHARE",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.ForShare(dialect, esperanto.LockOptions{NoWait: true})
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "FOR SHARE NOWAIT",
+		esperanto.MySQL:     "FOR SHARE NOWAIT",
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: fails,
+		esperanto.Oracle:    fails,
+	})
+}
+
+func TestHint(t *testing.T) {
+	t.Parallel()
+
+	hints := map[esperanto.Dialect]string{
+		esperanto.Postgres:  "SeqScan(t)",
+		esperanto.MySQL:     "NO_INDEX(t)",
+		esperanto.SQLServer: "RECOMPILE",
+		esperanto.Oracle:    "FULL(t)",
+	}
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Hint(dialect, superbasic.SQL("SELECT * FROM t WHERE id = ?", 1), hints)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "/*+ SeqScan(t) */ SELECT * FROM t WHERE id = $1",
+		esperanto.MySQL:     "SELECT /*+ NO_INDEX(t) */ * FROM t WHERE id = ?",
+		esperanto.Sqlite:    "SELECT * FROM t WHERE id = ?",
+		esperanto.SQLServer: "SELECT * FROM t WHERE id = @p1\nOPTION (RECOMPILE)",
+		esperanto.Oracle:    "SELECT /*+ FULL(t) */ * FROM t WHERE id = :1",
+	})
+
+	// MySQL has no SELECT to hint and the Sqlite hint would end the comment
+	invalid := map[esperanto.Dialect]string{
+		esperanto.MySQL:  "NO_INDEX(t)",
+		esperanto.Sqlite: "*/",
+	}
+
+	testSnapshot(t, func(dialect esperanto.Dialect) superbasic.Expression {
+		return esperanto.Hint(dialect, superbasic.SQL("DELETE FROM t"), invalid)
+	}, map[esperanto.Dialect]string{
+		esperanto.Postgres:  "DELETE FROM t",
+		esperanto.MySQL:     fails,
+		esperanto.Sqlite:    fails,
+		esperanto.SQLServer: "DELETE FROM t",
+		esperanto.Oracle:    "DELETE FROM t",
+	})
+}